@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/mateusfaustino/go-rest-api-III/configs"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database/migrations"
+)
+
+const usage = `Uso: migrate [-dir pasta] <comando>
+
+Comandos:
+  up            aplica todas as migrações pendentes
+  down [N]      reverte as últimas N migrações (padrão 1)
+  status        lista as migrações e se já foram aplicadas
+  create NOME   cria um arquivo de migração vazio
+`
+
+func main() {
+	dir := flag.String("dir", "internal/infra/database/migrations", "pasta onde as migrações são criadas")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// O comando create não precisa de banco de dados
+	if args[0] == "create" {
+		if len(args) < 2 {
+			log.Fatal("Informe o nome da migração")
+		}
+		path, err := migrations.Create(*dir, strings.Join(args[1:], "_"), time.Now())
+		if err != nil {
+			log.Fatalf("Erro ao criar migração: %v", err)
+		}
+		fmt.Println("Migração criada:", path)
+		return
+	}
+
+	// Carrega variáveis do .env
+	if err := godotenv.Load(); err != nil {
+		log.Println("Erro ao carregar .env, usando variáveis do sistema")
+	}
+
+	cfg, err := configs.LoadConfig(".")
+	if err != nil {
+		log.Fatalf("Erro ao carregar configurações: %v", err)
+	}
+
+	db, err := database.NewConnection(database.Config{
+		Driver:   cfg.DBDriver,
+		Host:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		User:     cfg.DBUser,
+		Password: cfg.DBPassword,
+		Name:     cfg.DBName,
+		SSLMode:  cfg.DBSSLMode,
+	})
+	if err != nil {
+		log.Fatalf("Erro ao conectar no banco de dados: %v", err)
+	}
+
+	migrator := migrations.NewMigrator(db)
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up()
+		for _, m := range done {
+			fmt.Printf("Aplicada: %s_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Erro ao aplicar migrações: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("Nenhuma migração pendente.")
+		}
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			n, err = strconv.Atoi(args[1])
+			if err != nil {
+				log.Fatalf("Número de migrações inválido: %s", args[1])
+			}
+		}
+		done, err := migrator.Down(n)
+		for _, m := range done {
+			fmt.Printf("Revertida: %s_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Erro ao reverter migrações: %v", err)
+		}
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Erro ao consultar migrações: %v", err)
+		}
+		for _, s := range status {
+			applied := "pendente"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s  %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
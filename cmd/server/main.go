@@ -5,21 +5,24 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	"github.com/go-chi/jwtauth"
 	"github.com/joho/godotenv"
 	"github.com/mateusfaustino/go-rest-api-III/configs"
 	_ "github.com/mateusfaustino/go-rest-api-III/docs"
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database/migrations"
 	seed "github.com/mateusfaustino/go-rest-api-III/internal/infra/database/seeds"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/search"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/handlers"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/middlewares"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/signer"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
 )
 
 // @title Swagger Example API
@@ -53,71 +56,263 @@ func main() {
 		log.Fatalf("Erro ao carregar configurações: %v", err)
 	}
 
-	// Obtém as variáveis de ambiente
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-
-	// String de conexão MySQL
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// Abre a conexão com o driver definido em DB_DRIVER
+	db, err := database.NewConnection(database.Config{
+		Driver:          cfg.DBDriver,
+		Host:            cfg.DBHost,
+		Port:            cfg.DBPort,
+		User:            cfg.DBUser,
+		Password:        cfg.DBPassword,
+		Name:            cfg.DBName,
+		SSLMode:         cfg.DBSSLMode,
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: time.Second * time.Duration(cfg.DBConnMaxLifetime),
+	})
 	if err != nil {
 		log.Fatalf("Erro ao conectar no banco de dados: %v", err)
 	}
 
-	fmt.Println("Conectado ao MySQL com sucesso!")
+	fmt.Printf("Conectado ao banco de dados (%s) com sucesso!\n", db.Dialector.Name())
+
+	// Não sobe com o schema desatualizado: as migrações rodam via cmd/migrate
+	pending, err := migrations.NewMigrator(db).Pending()
+	if err != nil {
+		log.Fatalf("Erro ao verificar migrações: %v", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Existem %d migrações pendentes (a primeira é %s_%s). Rode `go run ./cmd/migrate up` antes de iniciar o servidor.",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
 
-	// AutoMigrate para criar as tabelas automaticamente
-	db.AutoMigrate(&entity.Role{}, &entity.Product{}, &entity.User{})
 	seed.SeedRoles(db)
+	seed.SeedPermissions(db)
 	seed.SeedUsers(db)
+	seed.SeedCategories(db)
 	seed.SeedProducts(db)
 
 	productdb := database.NewProductDB(db)
+	categorydb := database.NewCategoryDB(db)
+	stockdb := database.NewStockDB(db)
+	cartdb := database.NewCartDB(db)
+	orderdb := database.NewOrderDB(db)
 	userdb := database.NewUserDb(db)
-	roledb := database.NewRoleDB(db)
+	roledb := database.NewRolePermissionCache(database.NewRoleDB(db), time.Minute)
+	permissiondb := database.NewPermissionDB(db)
+	refreshtokendb := database.NewRefreshTokenDB(db)
+	tokenrevocationdb := database.NewTokenRevocationCache(database.NewTokenRevocationDB(db), time.Minute)
+	passwordresetdb := database.NewPasswordResetTokenDB(db)
+	apikeydb := database.NewAPIKeyDB(db)
+	loginattemptdb := database.NewLoginAttemptDB(db)
+
+	// Remove periodicamente revogações de tokens que já expiraram
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := tokenrevocationdb.DeleteExpiredRevokedTokens(); err != nil {
+				log.Printf("Erro ao limpar tokens revogados: %v\n", err)
+			}
+			if err := passwordresetdb.DeleteExpiredPasswordResetTokens(); err != nil {
+				log.Printf("Erro ao limpar tokens de redefinição de senha: %v\n", err)
+			}
+			retention := time.Duration(cfg.LoginAttemptRetention) * time.Second
+			if err := loginattemptdb.DeleteLoginAttemptsBefore(time.Now().Add(-retention)); err != nil {
+				log.Printf("Erro ao limpar o histórico de logins: %v\n", err)
+			}
+			if err := loginattemptdb.DeleteLoginFailuresBefore(time.Now().Add(-retention)); err != nil {
+				log.Printf("Erro ao limpar as falhas de login de emails sem conta: %v\n", err)
+			}
+		}
+	}()
 
-	ProductHandler := handlers.NewProductHandler(productdb)
-	UserHandler := handlers.NewUserHandler(userdb, roledb, cfg.TokenAuth, cfg.JwtExpiresIn)
+	// Expurga os produtos que passaram do prazo de retenção da lixeira
+	go func() {
+		retention := time.Duration(cfg.TrashRetention) * time.Second
+		for range time.Tick(time.Hour) {
+			purged, err := productdb.PurgeDeletedProducts(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Erro ao expurgar a lixeira de produtos: %v\n", err)
+			} else if purged > 0 {
+				log.Printf("%d produtos expurgados da lixeira\n", purged)
+			}
+		}
+	}()
+
+	// Cursores de paginação assinados, aceitos por todas as listagens
+	cursors := cursor.NewCodec(signer.New([]byte(cfg.SigningSecret), "cursor"))
+
+	ProductHandler := handlers.NewProductHandler(productdb, categorydb, search.NewIndex(), cursors)
+	if err := ProductHandler.RebuildSearchIndex(); err != nil {
+		log.Fatalf("Erro ao montar o índice de busca: %v", err)
+	}
+	CategoryHandler := handlers.NewCategoryHandler(categorydb)
+	StockHandler := handlers.NewStockHandler(stockdb, productdb)
+	CartHandler := handlers.NewCartHandler(cartdb, productdb)
+	OrderHandler := handlers.NewOrderHandler(orderdb, cartdb, productdb, cursors)
+	UserHandler := handlers.NewUserHandler(userdb, roledb, refreshtokendb, tokenrevocationdb, database.NewMFARecoveryCodeDB(db), cfg.JWTKeys, cfg.JwtExpiresIn, cfg.JwtRefreshExpiresIn, handlers.EmailVerification{
+		Signer:    signer.New([]byte(cfg.SigningSecret), "email-verification"),
+		Mailer:    cfg.Mailer,
+		URL:       cfg.EmailVerificationURL,
+		ExpiresIn: cfg.EmailVerificationExpiresIn,
+		Required:  cfg.RequireEmailVerification,
+	}, handlers.MFAConfig{
+		Issuer: cfg.MFAIssuer,
+		Signer: signer.New([]byte(cfg.SigningSecret), "mfa-challenge"),
+	}, handlers.LoginProtection{
+		AttemptDB: loginattemptdb,
+		Lockout: entity.LoginLockout{
+			Threshold: cfg.LoginLockoutThreshold,
+			Duration:  time.Duration(cfg.LoginLockoutDuration) * time.Second,
+		},
+	})
+	PasswordHandler := handlers.NewPasswordHandler(userdb, passwordresetdb, refreshtokendb, tokenrevocationdb, cfg.Mailer, cfg.PasswordResetURL, cfg.PasswordResetExpiresIn)
+	RoleHandler := handlers.NewRoleHandler(roledb, permissiondb)
+	KeyHandler := handlers.NewKeyHandler(cfg.JWTKeys)
+	APIKeyHandler := handlers.NewAPIKeyHandler(apikeydb, permissiondb)
+
+	// As rotas administrativas exigem permissões, não nomes de roles
+	can := func(permission string) func(http.Handler) http.Handler {
+		return middlewares.RequirePermission(roledb, permission)
+	}
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.NotFound("route not found"))
+	})
 
 	// Rotas não autenticadas
 
+	r.Get("/.well-known/jwks.json", KeyHandler.GetJWKS)
+
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/login", UserHandler.GetJWT)
+		r.Post("/refresh", UserHandler.RefreshJWT)
 		r.Post("/register", UserHandler.CreateUser)
+		r.Post("/password/forgot", PasswordHandler.ForgotPassword)
+		r.Post("/password/reset", PasswordHandler.ResetPassword)
+		r.Post("/verify-email", UserHandler.VerifyEmail)
+		r.Post("/resend-verification", UserHandler.ResendVerification)
+		r.Post("/mfa/verify", UserHandler.VerifyMFA)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.Verifier(cfg.JWTKeys))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.TokenRevocationMiddleware(tokenrevocationdb))
+
+			r.Post("/logout", UserHandler.Logout)
+			r.Post("/logout-all", UserHandler.LogoutAll)
+		})
 	})
 
 	r.Route("/product", func(r chi.Router) {
 		r.Get("/", ProductHandler.GetProducts)
+		r.Get("/search", ProductHandler.SearchProducts)
 		r.Get("/{id}", ProductHandler.GetProduct)
 	})
 
+	r.Route("/category", func(r chi.Router) {
+		r.Get("/", CategoryHandler.GetCategoryTree)
+		r.Get("/{id}", CategoryHandler.GetCategory)
+	})
+
 	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8080/docs/doc.json")))
 	// Grupo para usuários autenticados
 	r.Group(func(r chi.Router) {
-		r.Use(jwtauth.Verifier(cfg.TokenAuth))
-		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.Verifier(cfg.JWTKeys))
+		r.Use(middlewares.APIKeyVerifier(apikeydb, userdb, roledb))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.TokenRevocationMiddleware(tokenrevocationdb))
 
 		r.Route("/user", func(r chi.Router) {
 			r.Get("/profile", UserHandler.ShowOwnProfile)
-			r.Put("/profile", UserHandler.UpdateOwnProfile)
+			r.With(middlewares.RequireSession).Put("/profile", UserHandler.UpdateOwnProfile)
+			r.Route("/api-keys", func(r chi.Router) {
+				r.Use(middlewares.RequireSession)
+				r.Get("/", APIKeyHandler.GetAPIKeys)
+				r.Post("/", APIKeyHandler.CreateAPIKey)
+				r.Put("/{id}", APIKeyHandler.UpdateAPIKey)
+				r.Delete("/{id}", APIKeyHandler.RevokeAPIKey)
+			})
+			r.Route("/mfa", func(r chi.Router) {
+				r.Use(middlewares.RequireSession)
+				r.Post("/totp/setup", UserHandler.SetupTOTP)
+				r.Post("/totp/confirm", UserHandler.ConfirmTOTP)
+				r.Post("/totp/disable", UserHandler.DisableMFA)
+				r.Post("/recovery-codes", UserHandler.RegenerateRecoveryCodes)
+			})
+			r.Get("/security/logins", UserHandler.GetLoginHistory)
 			r.Get("/{id}", UserHandler.GetUserById)
 		})
 
+		r.Route("/cart", func(r chi.Router) {
+			r.Get("/", CartHandler.GetCart)
+			r.Delete("/", CartHandler.ClearCart)
+			r.Post("/items", CartHandler.AddCartItem)
+			r.Put("/items/{productId}", CartHandler.UpdateCartItem)
+			r.Delete("/items/{productId}", CartHandler.RemoveCartItem)
+		})
+
+		r.Route("/order", func(r chi.Router) {
+			r.Get("/", OrderHandler.GetOwnOrders)
+			r.Post("/", OrderHandler.CreateOrder)
+			r.Get("/{id}", OrderHandler.GetOwnOrder)
+			r.Post("/{id}/cancel", OrderHandler.CancelOwnOrder)
+		})
+
 		r.Route("/admin", func(r chi.Router) {
-			r.Use(middlewares.RoleMiddleware(roledb, "manager", "admin"))
 			r.Route("/product", func(r chi.Router) {
-				r.Post("/", ProductHandler.CreateProduct)
-				r.Put("/{id}", ProductHandler.UpdateProduct)
-				r.Delete("/{id}", ProductHandler.DeleteProduct)
+				r.Group(func(r chi.Router) {
+					r.Use(can(entity.PermissionProductWrite))
+					r.Post("/", ProductHandler.CreateProduct)
+					r.Put("/{id}", ProductHandler.UpdateProduct)
+					r.Patch("/{id}", ProductHandler.PatchProduct)
+					r.Delete("/{id}", ProductHandler.DeleteProduct)
+					r.Get("/trash", ProductHandler.GetTrash)
+					r.Post("/{id}/restore", ProductHandler.RestoreProduct)
+				})
+				r.With(can(entity.PermissionProductPurge)).Delete("/{id}/purge", ProductHandler.PurgeProduct)
+				r.With(can(entity.PermissionStockRead)).Get("/low-stock", StockHandler.GetLowStockProducts)
+				r.With(can(entity.PermissionStockRead)).Get("/{id}/stock", StockHandler.GetStock)
+				r.With(can(entity.PermissionStockWrite)).Post("/{id}/stock", StockHandler.AdjustStock)
+			})
+			r.Route("/category", func(r chi.Router) {
+				r.Use(can(entity.PermissionCategoryWrite))
+				r.Post("/", CategoryHandler.CreateCategory)
+				r.Put("/{id}", CategoryHandler.UpdateCategory)
+				r.Delete("/{id}", CategoryHandler.DeleteCategory)
+			})
+			r.Route("/order", func(r chi.Router) {
+				r.With(can(entity.PermissionOrderRead)).Get("/", OrderHandler.GetOrders)
+				r.With(can(entity.PermissionOrderRead)).Get("/{id}", OrderHandler.GetOrder)
+				r.With(can(entity.PermissionOrderWrite)).Post("/{id}/transition", OrderHandler.TransitionOrder)
+			})
+			r.Route("/user", func(r chi.Router) {
+				r.With(can(entity.PermissionUserRead)).Get("/", UserHandler.ListUsers)
+				r.With(can(entity.PermissionUserRead)).Get("/{id}", UserHandler.GetUser)
+				r.Group(func(r chi.Router) {
+					r.Use(can(entity.PermissionUserWrite))
+					r.Delete("/{id}", UserHandler.DeleteUser)
+					r.Put("/{id}/role", UserHandler.UpdateUserRole)
+					r.Post("/{id}/disable", UserHandler.DisableUser)
+					r.Post("/{id}/enable", UserHandler.EnableUser)
+					r.Post("/{id}/unlock", UserHandler.UnlockUser)
+					r.Post("/{id}/force-password-reset", UserHandler.ForcePasswordReset)
+					r.Delete("/{id}/mfa", UserHandler.ResetUserMFA)
+				})
+			})
+			r.Route("/role", func(r chi.Router) {
+				r.With(can(entity.PermissionRoleRead)).Get("/", RoleHandler.GetRoles)
+				r.With(can(entity.PermissionRoleRead)).Get("/{id}", RoleHandler.GetRole)
+				r.Group(func(r chi.Router) {
+					r.Use(can(entity.PermissionRoleWrite))
+					r.Post("/", RoleHandler.CreateRole)
+					r.Put("/{id}", RoleHandler.UpdateRole)
+					r.Put("/{id}/permissions", RoleHandler.SetRolePermissions)
+					r.Put("/{id}/mfa", RoleHandler.SetRoleMFA)
+					r.Delete("/{id}", RoleHandler.DeleteRole)
+				})
 			})
+			r.With(can(entity.PermissionRoleRead)).Get("/permission", RoleHandler.GetPermissions)
 		})
 	})
 
@@ -2,24 +2,57 @@ package configs
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
-	"github.com/go-chi/jwtauth"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/jwtkeys"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/mailer"
 	"github.com/spf13/viper"
 )
 
 var cfg *conf
 
+// minSecretLength is the shortest JWT_SECRET and SIGNING_SECRET accepted, in
+// bytes: HS256 keys shouldn't be shorter than the hash.
+const minSecretLength = 32
+
 type conf struct {
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBHost        string `mapstructure:"DB_HOST"`
-	DBPort        string `mapstructure:"DB_PORT"`
-	DBUser        string `mapstructure:"DB_USER"`
-	DBPassword    string `mapstructure:"DB_PASSWORD"`
-	DBName        string `mapstructure:"DB_NAME"`
-	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
-	JWTSecret     string `mapstructure:"JWT_SECRET"`
-	JwtExpiresIn  int    `mapstructure:"JWT_EXPIRESIN"`
-	TokenAuth     *jwtauth.JWTAuth
+	DBDriver                   string `mapstructure:"DB_DRIVER"`
+	DBHost                     string `mapstructure:"DB_HOST"`
+	DBPort                     string `mapstructure:"DB_PORT"`
+	DBUser                     string `mapstructure:"DB_USER"`
+	DBPassword                 string `mapstructure:"DB_PASSWORD"`
+	DBName                     string `mapstructure:"DB_NAME"`
+	DBSSLMode                  string `mapstructure:"DB_SSL_MODE"`
+	DBMaxOpenConns             int    `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns             int    `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime          int    `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	WebServerPort              string `mapstructure:"WEB_SERVER_PORT"`
+	JWTSecret                  string `mapstructure:"JWT_SECRET"`
+	JwtExpiresIn               int    `mapstructure:"JWT_EXPIRESIN"`
+	JwtRefreshExpiresIn        int    `mapstructure:"JWT_REFRESH_EXPIRESIN"`
+	JWTPrivateKeyFile          string `mapstructure:"JWT_PRIVATE_KEY_FILE"`
+	JWTVerificationKeyFiles    string `mapstructure:"JWT_VERIFICATION_KEY_FILES"`
+	SigningSecret              string `mapstructure:"SIGNING_SECRET"`
+	TrashRetention             int    `mapstructure:"TRASH_RETENTION"`
+	MailDriver                 string `mapstructure:"MAIL_DRIVER"`
+	MailFrom                   string `mapstructure:"MAIL_FROM"`
+	MailDir                    string `mapstructure:"MAIL_DIR"`
+	SMTPHost                   string `mapstructure:"SMTP_HOST"`
+	SMTPPort                   int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername               string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword               string `mapstructure:"SMTP_PASSWORD"`
+	PasswordResetURL           string `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetExpiresIn     int    `mapstructure:"PASSWORD_RESET_EXPIRESIN"`
+	EmailVerificationURL       string `mapstructure:"EMAIL_VERIFICATION_URL"`
+	EmailVerificationExpiresIn int    `mapstructure:"EMAIL_VERIFICATION_EXPIRESIN"`
+	RequireEmailVerification   bool   `mapstructure:"REQUIRE_EMAIL_VERIFICATION"`
+	MFAIssuer                  string `mapstructure:"MFA_ISSUER"`
+	LoginLockoutThreshold      int    `mapstructure:"LOGIN_LOCKOUT_THRESHOLD"`
+	LoginLockoutDuration       int    `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginAttemptRetention      int    `mapstructure:"LOGIN_ATTEMPT_RETENTION"`
+	JWTKeys                    *jwtkeys.KeySet
+	Mailer                     mailer.Mailer
 }
 
 func LoadConfig(path string) (*conf, error) {
@@ -38,6 +71,133 @@ func LoadConfig(path string) (*conf, error) {
 	if err != nil { // Handle errors reading the config file
 		panic(fmt.Errorf("fatal error config file: %w", err))
 	}
-	cfg.TokenAuth = jwtauth.New("HS256", []byte(cfg.JWTSecret), nil)
+	cfg.JWTKeys, err = loadJWTKeys(cfg)
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %w", err))
+	}
+
+	// Refresh tokens valem 7 dias se nada for configurado
+	if cfg.JwtRefreshExpiresIn <= 0 {
+		cfg.JwtRefreshExpiresIn = 7 * 24 * 60 * 60
+	}
+
+	// Cursores e links assinados usam o segredo do JWT se nenhum for definido
+	if cfg.SigningSecret == "" {
+		cfg.SigningSecret = cfg.JWTSecret
+	}
+	// Com chave privada o JWT_SECRET pode estar vazio, e um segredo vazio
+	// deixaria qualquer um forjar links de verificação e desafios de MFA
+	if len(cfg.SigningSecret) < minSecretLength {
+		panic(fmt.Errorf("fatal error config file: SIGNING_SECRET (or JWT_SECRET) must have at least %d bytes", minSecretLength))
+	}
+
+	// Produtos ficam 30 dias na lixeira se nada for configurado
+	if cfg.TrashRetention <= 0 {
+		cfg.TrashRetention = 30 * 24 * 60 * 60
+	}
+
+	// Sem SMTP configurado, os emails são gravados em arquivos .eml
+	if cfg.MailDriver == "" {
+		cfg.MailDriver = "file"
+	}
+	if cfg.MailDir == "" {
+		cfg.MailDir = "mail"
+	}
+	if cfg.MailFrom == "" {
+		cfg.MailFrom = "no-reply@localhost"
+	}
+	if cfg.SMTPPort == 0 {
+		cfg.SMTPPort = 587
+	}
+	cfg.Mailer, err = mailer.New(mailer.Config{
+		Driver:       cfg.MailDriver,
+		From:         cfg.MailFrom,
+		Dir:          cfg.MailDir,
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUsername: cfg.SMTPUsername,
+		SMTPPassword: cfg.SMTPPassword,
+	})
+	if err != nil {
+		panic(fmt.Errorf("fatal error config file: %w", err))
+	}
+
+	// Links de redefinição de senha valem uma hora se nada for configurado
+	if cfg.PasswordResetExpiresIn <= 0 {
+		cfg.PasswordResetExpiresIn = 60 * 60
+	}
+	if cfg.PasswordResetURL == "" {
+		cfg.PasswordResetURL = "http://localhost:8080/reset-password"
+	}
+
+	// Links de verificação de email valem um dia se nada for configurado
+	if cfg.EmailVerificationExpiresIn <= 0 {
+		cfg.EmailVerificationExpiresIn = 24 * 60 * 60
+	}
+	if cfg.EmailVerificationURL == "" {
+		cfg.EmailVerificationURL = "http://localhost:8080/verify-email"
+	}
+
+	// Nome com que as contas aparecem nos aplicativos autenticadores
+	if cfg.MFAIssuer == "" {
+		cfg.MFAIssuer = "Go REST API"
+	}
+
+	// Dez senhas erradas seguidas bloqueiam a conta por 15 minutos
+	if cfg.LoginLockoutThreshold <= 0 {
+		cfg.LoginLockoutThreshold = 10
+	}
+	if cfg.LoginLockoutDuration <= 0 {
+		cfg.LoginLockoutDuration = 15 * 60
+	}
+	// O histórico de logins é guardado por 90 dias
+	if cfg.LoginAttemptRetention <= 0 {
+		cfg.LoginAttemptRetention = 90 * 24 * 60 * 60
+	}
 	return cfg, err
 }
+
+// loadJWTKeys builds the keys of the access tokens. With a private key file
+// the tokens are signed with it, by RS256, ES256 or EdDSA depending on the
+// key, and the verification key files are the previous keys still accepted
+// during a rotation. Otherwise they are signed with JWT_SECRET by HS256.
+func loadJWTKeys(cfg *conf) (*jwtkeys.KeySet, error) {
+	if cfg.JWTPrivateKeyFile == "" {
+		if len(cfg.JWTSecret) < minSecretLength {
+			return nil, fmt.Errorf("JWT_SECRET must have at least %d bytes", minSecretLength)
+		}
+		key, err := jwtkeys.NewSymmetricKey("default", []byte(cfg.JWTSecret))
+		if err != nil {
+			return nil, err
+		}
+		return jwtkeys.NewKeySet(key)
+	}
+
+	data, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signing, err := jwtkeys.ParsePrivateKeyPEM(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.JWTPrivateKeyFile, err)
+	}
+
+	var verification []*jwtkeys.Key
+	for _, file := range strings.Split(cfg.JWTVerificationKeyFiles, ",") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwtkeys.ParsePublicKeyPEM(data, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		verification = append(verification, key)
+	}
+
+	return jwtkeys.NewKeySet(signing, verification...)
+}
@@ -1,21 +1,114 @@
 package dto
 
-type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+)
+
+// ProductListOutput is a page of products with the pagination details.
+// Page is omitted when paging with a cursor.
+type ProductListOutput struct {
+	Items      []entity.Product `json:"items"`
+	Total      int64            `json:"total"`
+	Page       int              `json:"page,omitempty"`
+	Limit      int              `json:"limit"`
+	HasNext    bool             `json:"has_next"`
+	NextCursor string           `json:"next_cursor,omitempty"`
 }
 
-// UpdateProductInput represents the fields allowed when updating a product.
-// Only the Name and Price can be modified.
-type UpdateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+// ProductSearchResult is a product matching a search, with the relevance
+// score and the matched words of each field wrapped in <mark> tags.
+type ProductSearchResult struct {
+	Product    entity.Product    `json:"product"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type AdjustStockInput struct {
+	Type     string `json:"type" validate:"required,oneof=receipt sale adjustment return"`
+	Quantity int    `json:"quantity" validate:"required"`
+	Reason   string `json:"reason"`
+}
+
+type StockOutput struct {
+	ProductID         string                 `json:"product_id"`
+	Stock             int                    `json:"stock"`
+	LowStockThreshold int                    `json:"low_stock_threshold"`
+	LowStock          bool                   `json:"low_stock"`
+	Movements         []entity.StockMovement `json:"movements"`
+}
+
+type CreateCategoryInput struct {
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+// UpdateCategoryInput renames or moves a category. A null or missing
+// parent_id moves the category to the root of the tree.
+type UpdateCategoryInput struct {
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
 }
 
 type CreateUserInput struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// CreateRoleInput creates a role with the given permissions, by name.
+type CreateRoleInput struct {
+	Name        string   `json:"name" validate:"required,max=100"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleInput struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// SetRolePermissionsInput replaces the permissions of a role. An empty list
+// removes all of them.
+type SetRolePermissionsInput struct {
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// SetRoleMFAInput turns on or off the two-factor authentication required by
+// a role.
+type SetRoleMFAInput struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+// UpdateUserRoleInput moves a user to another role.
+type UpdateUserRoleInput struct {
+	RoleID string `json:"role_id" validate:"required"`
+}
+
+// UserListOutput is a page of users with the pagination details.
+type UserListOutput struct {
+	Items   []entity.User `json:"items"`
+	Total   int64         `json:"total"`
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+	HasNext bool          `json:"has_next"`
+}
+
+// LoginAttemptListOutput is a page of login attempts with the pagination
+// details.
+type LoginAttemptListOutput struct {
+	Items   []entity.LoginAttempt `json:"items"`
+	Total   int64                 `json:"total"`
+	Page    int                   `json:"page"`
+	Limit   int                   `json:"limit"`
+	HasNext bool                  `json:"has_next"`
 }
 
 type GetJWTInput struct {
@@ -29,5 +122,102 @@ type UpdateOwnProfileInput struct {
 	NewPassword string `json:"new_password"`
 }
 type GetJWTOutput struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordInput takes the token sent by email and the new password.
+type ResetPasswordInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// MFAChallengeOutput is what the login answers for accounts with two-factor
+// authentication: the token is exchanged with a code at /auth/mfa/verify.
+type MFAChallengeOutput struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// VerifyMFAInput takes a TOTP code or a recovery code.
+type VerifyMFAInput struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type TOTPSetupOutput struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type ConfirmTOTPInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// DisableMFAInput asks for the password and a TOTP or recovery code.
+type DisableMFAInput struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// CreateAPIKeyInput creates an API key. Scopes limit it to some of the
+// permissions of the role, by name; without them the key has all of them.
+// Keys without expires_at never expire.
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UpdateAPIKeyInput struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreateAPIKeyOutput is the new key with the plain key, which is shown only
+// this time.
+type CreateAPIKeyOutput struct {
+	entity.APIKey
+	Key string `json:"key"`
+}
+
+type RefreshJWTInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AddCartItemInput struct {
+	ProductID string `json:"product_id" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+}
+
+type UpdateCartItemInput struct {
+	Quantity int `json:"quantity" validate:"required,min=1"`
+}
+
+type OrderItemInput struct {
+	ProductID string `json:"product_id" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+}
+
+// CreateOrderInput lists the items to order. Without items the order is
+// placed from the user's cart.
+type CreateOrderInput struct {
+	Items []OrderItemInput `json:"items" validate:"dive"`
+}
+
+type TransitionOrderInput struct {
+	Status string `json:"status" validate:"required,oneof=pending paid shipped delivered cancelled refunded"`
+	Note   string `json:"note" validate:"max=255"`
 }
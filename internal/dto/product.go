@@ -0,0 +1,36 @@
+package dto
+
+// The Swagger generator only resolves the package of a type by its name, so
+// this file imports pkg/entity without an alias.
+import "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+
+// CreateProductInput takes the price as {"amount": "12.90", "currency": "BRL"},
+// as a decimal string like "12.90" or "12.90 USD", or as a number. Amounts
+// without a currency are in the default currency.
+type CreateProductInput struct {
+	Name              string       `json:"name"`
+	Price             entity.Money `json:"price"`
+	CategoryIDs       []string     `json:"category_ids"`
+	LowStockThreshold int          `json:"low_stock_threshold"`
+}
+
+// UpdateProductInput represents the fields allowed when updating a product.
+// Only the Name, Price, categories and low stock threshold can be modified;
+// the stock itself changes through the stock endpoints. Omitting category_ids
+// keeps the current categories, while an empty list removes all of them.
+type UpdateProductInput struct {
+	Name              string        `json:"name"`
+	Price             *entity.Money `json:"price"`
+	CategoryIDs       []string      `json:"category_ids"`
+	LowStockThreshold *int          `json:"low_stock_threshold"`
+}
+
+// ProductDocument is the editable view of a product that PATCH requests are
+// applied to. Paths of a JSON Patch and members of a merge patch refer to
+// these fields.
+type ProductDocument struct {
+	Name              string       `json:"name"`
+	Price             entity.Money `json:"price"`
+	CategoryIDs       []string     `json:"category_ids"`
+	LowStockThreshold int          `json:"low_stock_threshold"`
+}
@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to spot.
+const APIKeyPrefix = "ak_"
+
+var (
+	ErrAPIKeyExpired         = errors.New("API key expired")
+	ErrAPIKeyRevoked         = errors.New("API key revoked")
+	ErrInvalidAPIKey         = errors.New("Invalid API key")
+	ErrAPIKeyExpiresInPast   = errors.New("API key expiration must be in the future")
+	ErrAPIKeyScopeNotGranted = errors.New("API key scopes must be granted to the role")
+)
+
+// APIKey lets scripts call the API as a user without logging in. Only the
+// hash of the key is stored; the prefix is kept in clear so users can tell
+// their keys apart. Scopes, when set, limit the permissions of the key to
+// those of them still granted to the user's role.
+type APIKey struct {
+	ID         entity.ID    `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     entity.ID    `json:"user_id" gorm:"type:char(36);index"`
+	Name       string       `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string       `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string       `json:"-" gorm:"type:char(64);uniqueIndex"`
+	Scopes     []Permission `json:"scopes" gorm:"many2many:api_key_scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// NewAPIKey creates a key for the user and returns it together with the
+// plain key, which is shown only once. A nil expiresAt never expires.
+func NewAPIKey(userID entity.ID, name string, scopes []Permission, expiresAt *time.Time) (*APIKey, string, error) {
+	if name == "" {
+		return nil, "", ErrNameIsRequired
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", ErrAPIKeyExpiresInPast
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret, err := entity.NewRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Como "ak_1f2e3d4c_<segredo>"; o começo fica visível nas listagens
+	prefix := APIKeyPrefix + hex.EncodeToString(b)
+	plain := prefix + "_" + secret
+
+	return &APIKey{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   entity.HashToken(plain),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, plain, nil
+}
+
+// HashAPIKey returns the hash a key is stored under, rejecting values that
+// can't be API keys.
+func HashAPIKey(plain string) (string, error) {
+	if !strings.HasPrefix(plain, APIKeyPrefix) {
+		return "", ErrInvalidAPIKey
+	}
+	return entity.HashToken(plain), nil
+}
+
+func (k *APIKey) Rename(name string) error {
+	if name == "" {
+		return ErrNameIsRequired
+	}
+	k.Name = name
+	return nil
+}
+
+// ScopeNames returns the names of the permissions the key is limited to,
+// empty when it has every permission of the role.
+func (k *APIKey) ScopeNames() []string {
+	names := make([]string, len(k.Scopes))
+	for i, scope := range k.Scopes {
+		names[i] = scope.Name
+	}
+	return names
+}
+
+// Validate checks whether the key can still be used.
+func (k *APIKey) Validate() error {
+	if k.RevokedAt != nil {
+		return ErrAPIKeyRevoked
+	}
+
+	if k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) {
+		return ErrAPIKeyExpired
+	}
+
+	return nil
+}
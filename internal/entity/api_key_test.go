@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	userID := entityPkg.NewID()
+	key, plain, err := NewAPIKey(userID, "deploy", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, key.UserID)
+	assert.True(t, strings.HasPrefix(plain, key.Prefix+"_"))
+	assert.True(t, strings.HasPrefix(key.Prefix, APIKeyPrefix))
+	assert.Len(t, key.Prefix, len(APIKeyPrefix)+8)
+
+	hash, err := HashAPIKey(plain)
+	assert.NoError(t, err)
+	assert.Equal(t, key.KeyHash, hash)
+	assert.NotContains(t, key.KeyHash, plain)
+	assert.NoError(t, key.Validate())
+
+	_, err = HashAPIKey("not-a-key")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, _, err = NewAPIKey(userID, "", nil, nil)
+	assert.ErrorIs(t, err, ErrNameIsRequired)
+
+	past := time.Now().Add(-time.Minute)
+	_, _, err = NewAPIKey(userID, "deploy", nil, &past)
+	assert.ErrorIs(t, err, ErrAPIKeyExpiresInPast)
+}
+
+func TestAPIKey_Validate(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	key, _, _ := NewAPIKey(entityPkg.NewID(), "deploy", nil, &soon)
+	assert.NoError(t, key.Validate())
+
+	expired := time.Now().Add(-time.Second)
+	key.ExpiresAt = &expired
+	assert.ErrorIs(t, key.Validate(), ErrAPIKeyExpired)
+
+	now := time.Now()
+	key.RevokedAt = &now
+	assert.ErrorIs(t, key.Validate(), ErrAPIKeyRevoked)
+}
+
+func TestAPIKey_ScopeNames(t *testing.T) {
+	key := &APIKey{Scopes: []Permission{{Name: PermissionProductWrite}, {Name: PermissionStockRead}}}
+	assert.Equal(t, []string{PermissionProductWrite, PermissionStockRead}, key.ScopeNames())
+	assert.Empty(t, (&APIKey{}).ScopeNames())
+}
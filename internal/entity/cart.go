@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrCartItemNotFound = errors.New("Cart item not found")
+	ErrCartChanged      = errors.New("Cart changed while the order was placed, try again")
+)
+
+// Cart is the persistent shopping cart of a user. Each item keeps the name
+// and price the product had when it was added.
+type Cart struct {
+	ID        entity.ID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    entity.ID  `json:"user_id" gorm:"type:char(36);uniqueIndex"`
+	Items     []CartItem `json:"items" gorm:"foreignKey:CartID"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type CartItem struct {
+	ID        entity.ID    `json:"id" gorm:"type:char(36);primaryKey"`
+	CartID    entity.ID    `json:"cart_id" gorm:"type:char(36);uniqueIndex:idx_cart_items_cart_product"`
+	ProductID entity.ID    `json:"product_id" gorm:"type:char(36);uniqueIndex:idx_cart_items_cart_product"`
+	Name      string       `json:"name" gorm:"type:varchar(255)"`
+	UnitPrice entity.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	Quantity  int          `json:"quantity" gorm:"not null"`
+	AddedAt   time.Time    `json:"added_at"`
+}
+
+// CartLine is a cart item compared against the current product data.
+type CartLine struct {
+	CartItem
+	CurrentPrice *entity.Money `json:"current_price"`
+	Subtotal     entity.Money  `json:"subtotal"`
+	Unavailable  bool          `json:"unavailable"`
+	PriceChanged bool          `json:"price_changed"`
+}
+
+// CartSummary is what the client sees: items flagged when their product was
+// removed or repriced, plus the totals of the items that can still be bought.
+type CartSummary struct {
+	ID        entity.ID    `json:"id"`
+	Items     []CartLine   `json:"items"`
+	ItemCount int          `json:"item_count"`
+	Total     entity.Money `json:"total"`
+	HasIssues bool         `json:"has_issues"`
+}
+
+func NewCart(userID entity.ID) *Cart {
+	now := time.Now()
+	return &Cart{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Items:     []CartItem{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// NewCartItem snapshots the product name and price.
+func NewCartItem(cartID entity.ID, product *Product, quantity int) (*CartItem, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	return &CartItem{
+		ID:        entity.NewID(),
+		CartID:    cartID,
+		ProductID: product.ID,
+		Name:      product.Name,
+		UnitPrice: product.Price,
+		Quantity:  quantity,
+		AddedAt:   time.Now(),
+	}, nil
+}
+
+// Summarize compares every item with the current products, indexed by ID.
+// Items whose product is missing are flagged as unavailable and left out of
+// the totals; repriced items are flagged but keep their snapshot price. The
+// total is in the currency of the first item, and items in another currency
+// are flagged and left out as well.
+func (c *Cart) Summarize(products map[entity.ID]Product) CartSummary {
+	summary := CartSummary{ID: c.ID, Items: []CartLine{}, Total: entity.NewMoney(0, entity.DefaultCurrency)}
+	if len(c.Items) > 0 {
+		summary.Total = entity.NewMoney(0, c.Items[0].UnitPrice.Currency)
+	}
+
+	for _, item := range c.Items {
+		line := CartLine{CartItem: item}
+
+		product, ok := products[item.ProductID]
+		if !ok {
+			line.Unavailable = true
+			summary.HasIssues = true
+			summary.Items = append(summary.Items, line)
+			continue
+		}
+
+		price := product.Price
+		line.CurrentPrice = &price
+		if !price.Equal(item.UnitPrice) {
+			line.PriceChanged = true
+			summary.HasIssues = true
+		}
+
+		line.Subtotal = item.UnitPrice.Mul(int64(item.Quantity))
+		total, err := summary.Total.Add(line.Subtotal)
+		if err != nil {
+			summary.HasIssues = true
+			summary.Items = append(summary.Items, line)
+			continue
+		}
+
+		summary.Total = total
+		summary.ItemCount += item.Quantity
+		summary.Items = append(summary.Items, line)
+	}
+
+	return summary
+}
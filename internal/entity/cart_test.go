@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"testing"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCartItem(t *testing.T) {
+	cart := NewCart(entityPkg.NewID())
+	product, _ := NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+
+	item, err := NewCartItem(cart.ID, product, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, cart.ID, item.CartID)
+	assert.Equal(t, product.ID, item.ProductID)
+	assert.Equal(t, "Blusa", item.Name)
+	assert.Equal(t, entityPkg.MustParseMoney("9.99", "BRL"), item.UnitPrice)
+	assert.Equal(t, 2, item.Quantity)
+
+	_, err = NewCartItem(cart.ID, product, 0)
+	assert.Equal(t, ErrInvalidQuantity, err)
+}
+
+func TestCartSummarize(t *testing.T) {
+	cart := NewCart(entityPkg.NewID())
+	shirt, _ := NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	pants, _ := NewProduct("Calça", entityPkg.MustParseMoney("19.90", "BRL"))
+	shoes, _ := NewProduct("Tênis", entityPkg.MustParseMoney("99.90", "BRL"))
+
+	for _, p := range []*Product{shirt, pants, shoes} {
+		item, _ := NewCartItem(cart.ID, p, 3)
+		cart.Items = append(cart.Items, *item)
+	}
+
+	// Calça foi reajustada e o tênis foi removido do catálogo
+	repriced := *pants
+	repriced.Price = entityPkg.MustParseMoney("24.90", "BRL")
+	summary := cart.Summarize(map[entityPkg.ID]Product{
+		shirt.ID: *shirt,
+		pants.ID: repriced,
+	})
+
+	assert.True(t, summary.HasIssues)
+	assert.Len(t, summary.Items, 3)
+
+	assert.False(t, summary.Items[0].PriceChanged)
+	assert.Equal(t, entityPkg.MustParseMoney("29.97", "BRL"), summary.Items[0].Subtotal)
+
+	assert.True(t, summary.Items[1].PriceChanged)
+	assert.Equal(t, entityPkg.MustParseMoney("24.90", "BRL"), *summary.Items[1].CurrentPrice)
+	assert.Equal(t, entityPkg.MustParseMoney("59.70", "BRL"), summary.Items[1].Subtotal)
+
+	assert.True(t, summary.Items[2].Unavailable)
+	assert.Nil(t, summary.Items[2].CurrentPrice)
+
+	assert.Equal(t, entityPkg.MustParseMoney("89.67", "BRL"), summary.Total)
+	assert.Equal(t, 6, summary.ItemCount)
+}
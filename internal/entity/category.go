@@ -0,0 +1,112 @@
+package entity
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrCategoryCycle = errors.New("Category cannot be moved under itself or one of its descendants")
+)
+
+type Category struct {
+	ID        entity.ID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Name      string     `json:"name" gorm:"type:varchar(255);not null"`
+	ParentID  *entity.ID `json:"parent_id" gorm:"type:char(36);index"`
+	Children  []Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func NewCategory(name string, parentID *entity.ID) (*Category, error) {
+	category := &Category{
+		ID:        entity.NewID(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := category.ValidateCategory(); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (c *Category) ValidateCategory() error {
+	if c.ID.String() == "" {
+		return ErrIDIsRequired
+	}
+
+	if c.Name == "" {
+		return ErrNameIsRequired
+	}
+
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return ErrCategoryCycle
+	}
+
+	return nil
+}
+
+// BuildCategoryTree nests a flat list of categories under their parents and
+// returns the roots. Categories whose parent is not in the list are treated
+// as roots. Siblings are sorted by name.
+func BuildCategoryTree(categories []Category) []Category {
+	byParent := make(map[entity.ID][]Category)
+	known := make(map[entity.ID]bool, len(categories))
+	for _, c := range categories {
+		known[c.ID] = true
+	}
+
+	var roots []Category
+	for _, c := range categories {
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+			continue
+		}
+		byParent[*c.ParentID] = append(byParent[*c.ParentID], c)
+	}
+
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+		for i := range nodes {
+			nodes[i].Children = attach(byParent[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	return attach(roots)
+}
+
+// DescendantIDs returns the IDs of every category below root in the given
+// flat list, not including root itself.
+func DescendantIDs(categories []Category, root entity.ID) []entity.ID {
+	byParent := make(map[entity.ID][]entity.ID)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			byParent[*c.ParentID] = append(byParent[*c.ParentID], c.ID)
+		}
+	}
+
+	var ids []entity.ID
+	visited := map[entity.ID]bool{root: true}
+	queue := []entity.ID{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range byParent[current] {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			ids = append(ids, child)
+			queue = append(queue, child)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCategory(t *testing.T) {
+	category, err := NewCategory("Electronics", nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, category)
+	assert.NotEmpty(t, category.ID)
+	assert.Equal(t, "Electronics", category.Name)
+	assert.Nil(t, category.ParentID)
+
+	child, err := NewCategory("Audio", &category.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, category.ID, *child.ParentID)
+}
+
+func TestCategoryWhenNameIsRequired(t *testing.T) {
+	category, err := NewCategory("", nil)
+	assert.Nil(t, category)
+	assert.Equal(t, ErrNameIsRequired, err)
+}
+
+func TestCategoryCannotBeItsOwnParent(t *testing.T) {
+	category, _ := NewCategory("Electronics", nil)
+	category.ParentID = &category.ID
+	assert.Equal(t, ErrCategoryCycle, category.ValidateCategory())
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	electronics, _ := NewCategory("Electronics", nil)
+	kitchen, _ := NewCategory("Kitchen", nil)
+	gaming, _ := NewCategory("Gaming", &electronics.ID)
+	audio, _ := NewCategory("Audio", &electronics.ID)
+	headphones, _ := NewCategory("Headphones", &audio.ID)
+
+	tree := BuildCategoryTree([]Category{*headphones, *kitchen, *gaming, *electronics, *audio})
+
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Electronics", tree[0].Name)
+	assert.Equal(t, "Kitchen", tree[1].Name)
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "Audio", tree[0].Children[0].Name)
+	assert.Equal(t, "Gaming", tree[0].Children[1].Name)
+	assert.Equal(t, "Headphones", tree[0].Children[0].Children[0].Name)
+}
+
+func TestDescendantIDs(t *testing.T) {
+	electronics, _ := NewCategory("Electronics", nil)
+	audio, _ := NewCategory("Audio", &electronics.ID)
+	headphones, _ := NewCategory("Headphones", &audio.ID)
+	kitchen, _ := NewCategory("Kitchen", nil)
+	all := []Category{*electronics, *audio, *headphones, *kitchen}
+
+	ids := DescendantIDs(all, electronics.ID)
+	assert.ElementsMatch(t, []interface{}{audio.ID, headphones.ID}, ids)
+	assert.Empty(t, DescendantIDs(all, kitchen.ID))
+}
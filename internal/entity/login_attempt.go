@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/ratelimit"
+)
+
+// Outcomes of a login attempt.
+const (
+	LoginSucceeded   = "success"
+	LoginMFARequired = "mfa_required"
+	LoginMFAFailed   = "mfa_failed"
+	LoginFailed      = "invalid_credentials"
+	LoginDenied      = "denied"
+	LoginLocked      = "locked"
+	LoginThrottled   = "throttled"
+)
+
+const (
+	maxUserAgentLength = 255
+
+	// The first failures in a row cost nothing, so typos don't lock anyone
+	// out; the next ones block the account for one second, doubling each time.
+	loginFreeFailures = 3
+	loginBackoffBase  = time.Second
+)
+
+// LoginAttempt records a try to log in. Attempts with an unknown email have
+// no user.
+type LoginAttempt struct {
+	ID        entity.ID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    *entity.ID `json:"user_id" gorm:"type:char(36);index"`
+	Email     string     `json:"email" gorm:"type:varchar(255)"`
+	IP        string     `json:"ip" gorm:"type:varchar(45)"`
+	UserAgent string     `json:"user_agent" gorm:"type:varchar(255)"`
+	Outcome   string     `json:"outcome" gorm:"type:varchar(20)"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+// NormalizeEmail returns the email as login attempts and failures are keyed:
+// trimmed and in lower case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func NewLoginAttempt(userID *entity.ID, email, ip, userAgent, outcome string) *LoginAttempt {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return &LoginAttempt{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Email:     NormalizeEmail(email),
+		IP:        ip,
+		UserAgent: userAgent,
+		Outcome:   outcome,
+		CreatedAt: time.Now(),
+	}
+}
+
+// LoginLockout is how an account is protected from password guessing: each
+// failure after the first few blocks the login for twice as long as the one
+// before, and Threshold failures in a row lock it for Duration.
+type LoginLockout struct {
+	Threshold int
+	Duration  time.Duration
+}
+
+// Until returns when an account with the given failures in a row can log in
+// again, nil when it can right away.
+func (l LoginLockout) Until(failures int, now time.Time) *time.Time {
+	delay := ratelimit.BackoffDelay(failures, loginFreeFailures, loginBackoffBase, l.Duration)
+	if failures >= l.Threshold {
+		delay = l.Duration
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	until := now.Add(delay)
+	return &until
+}
+
+// LoginFailure counts the wrong logins in a row of an email without an
+// account. Unknown emails are locked out like accounts, at the same point,
+// so the lockout doesn't tell which emails are registered. Failures are
+// forgotten with the login history.
+type LoginFailure struct {
+	Email       string     `json:"email" gorm:"type:varchar(255);primaryKey"`
+	Failures    int        `json:"failures" gorm:"not null;default:0"`
+	LockedUntil *time.Time `json:"locked_until"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"index"`
+}
+
+// LoginLockedFor returns how long logins with the email are still locked.
+func (f *LoginFailure) LoginLockedFor(now time.Time) time.Duration {
+	if f.LockedUntil == nil || !f.LockedUntil.After(now) {
+		return 0
+	}
+	return f.LockedUntil.Sub(now)
+}
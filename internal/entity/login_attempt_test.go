@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLoginAttempt(t *testing.T) {
+	userID := entityPkg.NewID()
+	attempt := NewLoginAttempt(&userID, " Ana@Example.com", "10.0.0.1", strings.Repeat("a", 300), LoginFailed)
+	assert.Equal(t, &userID, attempt.UserID)
+	assert.Equal(t, "ana@example.com", attempt.Email)
+	assert.Len(t, attempt.UserAgent, 255)
+	assert.Equal(t, LoginFailed, attempt.Outcome)
+}
+
+func TestLoginLockout_Until(t *testing.T) {
+	lockout := LoginLockout{Threshold: 10, Duration: 15 * time.Minute}
+	now := time.Now()
+
+	assert.Nil(t, lockout.Until(1, now))
+	assert.Nil(t, lockout.Until(3, now))
+	assert.Equal(t, now.Add(time.Second), *lockout.Until(4, now))
+	assert.Equal(t, now.Add(8*time.Second), *lockout.Until(7, now))
+	assert.Equal(t, now.Add(15*time.Minute), *lockout.Until(10, now))
+	assert.Equal(t, now.Add(15*time.Minute), *lockout.Until(25, now))
+}
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time.
+const RecoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFARecoveryCode replaces a TOTP code once, for users who lost their
+// authenticator app. Only the hash is stored.
+type MFARecoveryCode struct {
+	ID        entity.ID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    entity.ID  `json:"user_id" gorm:"type:char(36);index"`
+	CodeHash  string     `json:"-" gorm:"type:char(64);index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewMFARecoveryCodes creates a set of recovery codes for the user and
+// returns them together with the plain codes that are shown to the user.
+func NewMFARecoveryCodes(userID entity.ID) ([]MFARecoveryCode, []string, error) {
+	now := time.Now()
+	codes := make([]MFARecoveryCode, RecoveryCodeCount)
+	plain := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		// Dez caracteres em dois grupos, como "k4p2x-9qrtm"
+		text := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		plain[i] = text[:5] + "-" + text[5:]
+
+		codes[i] = MFARecoveryCode{
+			ID:        entity.NewID(),
+			UserID:    userID,
+			CodeHash:  HashRecoveryCode(plain[i]),
+			CreatedAt: now,
+		}
+	}
+	return codes, plain, nil
+}
+
+// HashRecoveryCode hashes a recovery code as typed by the user, ignoring
+// case, spaces and dashes.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return entity.HashToken(code)
+}
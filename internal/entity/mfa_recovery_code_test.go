@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMFARecoveryCodes(t *testing.T) {
+	userID := entityPkg.NewID()
+	codes, plain, err := NewMFARecoveryCodes(userID)
+	assert.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+	assert.Len(t, plain, RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		assert.Regexp(t, regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`), plain[i])
+		assert.Equal(t, userID, code.UserID)
+		assert.Equal(t, HashRecoveryCode(plain[i]), code.CodeHash)
+		assert.False(t, seen[plain[i]])
+		seen[plain[i]] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	// Maiúsculas, espaços e hífens não importam
+	expected := HashRecoveryCode("k4p2x-9qrtm")
+	assert.Equal(t, expected, HashRecoveryCode("K4P2X9QRTM"))
+	assert.Equal(t, expected, HashRecoveryCode(" k4p2x 9qrtm"))
+	assert.NotEqual(t, expected, HashRecoveryCode(strings.Repeat("a", 10)))
+}
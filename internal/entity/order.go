@@ -0,0 +1,174 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrOrderIsEmpty        = errors.New("Order must have at least one item")
+	ErrInvalidOrderStatus  = errors.New("Invalid order status")
+	ErrInvalidTransition   = errors.New("Invalid order status transition")
+	ErrOrderStatusConflict = errors.New("Order status was changed by another request")
+)
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions lists, for each status, the statuses it can move to.
+// Cancelled and refunded orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderCancelled, OrderRefunded},
+	OrderShipped:   {OrderDelivered},
+	OrderDelivered: {OrderRefunded},
+	OrderCancelled: {},
+	OrderRefunded:  {},
+}
+
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether the transition table allows moving from s
+// to the given status.
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+type Order struct {
+	ID          entity.ID         `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID      entity.ID         `json:"user_id" gorm:"type:char(36);index"`
+	Status      OrderStatus       `json:"status" gorm:"type:varchar(20);not null;index"`
+	Total       entity.Money      `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	Items       []OrderItem       `json:"items" gorm:"foreignKey:OrderID"`
+	Transitions []OrderTransition `json:"transitions" gorm:"foreignKey:OrderID"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// OrderItem keeps the product name and price charged when the order was
+// placed.
+type OrderItem struct {
+	ID        entity.ID    `json:"id" gorm:"type:char(36);primaryKey"`
+	OrderID   entity.ID    `json:"order_id" gorm:"type:char(36);index"`
+	ProductID entity.ID    `json:"product_id" gorm:"type:char(36);index"`
+	Name      string       `json:"name" gorm:"type:varchar(255)"`
+	UnitPrice entity.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	Quantity  int          `json:"quantity" gorm:"not null"`
+	Subtotal  entity.Money `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+}
+
+// OrderTransition is an entry of the order history. The creation of the order
+// is recorded as a transition with an empty From.
+type OrderTransition struct {
+	ID        entity.ID   `json:"id" gorm:"type:char(36);primaryKey"`
+	OrderID   entity.ID   `json:"order_id" gorm:"type:char(36);index"`
+	From      OrderStatus `json:"from" gorm:"column:from_status;type:varchar(20)"`
+	To        OrderStatus `json:"to" gorm:"column:to_status;type:varchar(20);not null"`
+	ActorID   *entity.ID  `json:"actor_id" gorm:"type:char(36)"`
+	Note      string      `json:"note" gorm:"type:varchar(255)"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Restocks reports whether the goods of the order go back to the stock with
+// this transition: always on cancellation, and on refunds of orders that
+// were never shipped.
+func (t *OrderTransition) Restocks() bool {
+	return t.To == OrderCancelled || (t.To == OrderRefunded && t.From == OrderPaid)
+}
+
+// NewOrderItem prices the item with the current product data.
+func NewOrderItem(product *Product, quantity int) (*OrderItem, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	return &OrderItem{
+		ID:        entity.NewID(),
+		ProductID: product.ID,
+		Name:      product.Name,
+		UnitPrice: product.Price,
+		Quantity:  quantity,
+		Subtotal:  product.Price.Mul(int64(quantity)),
+	}, nil
+}
+
+// NewOrder creates a pending order for the user, recording the creation in
+// its history. Every item must be in the same currency.
+func NewOrder(userID entity.ID, items []OrderItem) (*Order, error) {
+	if len(items) == 0 {
+		return nil, ErrOrderIsEmpty
+	}
+
+	now := time.Now()
+	order := &Order{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Status:    OrderPending,
+		Items:     items,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	for i := range order.Items {
+		order.Items[i].OrderID = order.ID
+		total, err := order.Total.Add(order.Items[i].Subtotal)
+		if err != nil {
+			return nil, err
+		}
+		order.Total = total
+	}
+
+	order.Transitions = []OrderTransition{{
+		ID:        entity.NewID(),
+		OrderID:   order.ID,
+		To:        OrderPending,
+		ActorID:   &userID,
+		CreatedAt: now,
+	}}
+
+	return order, nil
+}
+
+// Transition moves the order to the given status, returning the history
+// entry to be persisted.
+func (o *Order) Transition(to OrderStatus, actorID *entity.ID, note string) (*OrderTransition, error) {
+	if !to.IsValid() {
+		return nil, ErrInvalidOrderStatus
+	}
+	if !o.Status.CanTransitionTo(to) {
+		return nil, ErrInvalidTransition
+	}
+
+	transition := &OrderTransition{
+		ID:        entity.NewID(),
+		OrderID:   o.ID,
+		From:      o.Status,
+		To:        to,
+		ActorID:   actorID,
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+
+	o.Status = to
+	o.UpdatedAt = transition.CreatedAt
+	o.Transitions = append(o.Transitions, *transition)
+	return transition, nil
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"testing"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOrder(t *testing.T) {
+	userID := entityPkg.NewID()
+	shirt, _ := NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	pants, _ := NewProduct("Calça", entityPkg.MustParseMoney("19.90", "BRL"))
+
+	shirtItem, err := NewOrderItem(shirt, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, entityPkg.MustParseMoney("29.97", "BRL"), shirtItem.Subtotal)
+	pantsItem, _ := NewOrderItem(pants, 1)
+
+	order, err := NewOrder(userID, []OrderItem{*shirtItem, *pantsItem})
+	assert.NoError(t, err)
+	assert.Equal(t, OrderPending, order.Status)
+	assert.Equal(t, entityPkg.MustParseMoney("49.87", "BRL"), order.Total)
+	assert.Equal(t, order.ID, order.Items[0].OrderID)
+	assert.Len(t, order.Transitions, 1)
+	assert.Equal(t, OrderPending, order.Transitions[0].To)
+	assert.Equal(t, userID, *order.Transitions[0].ActorID)
+
+	_, err = NewOrderItem(shirt, 0)
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	_, err = NewOrder(userID, nil)
+	assert.Equal(t, ErrOrderIsEmpty, err)
+}
+
+func TestOrderTransition(t *testing.T) {
+	product, _ := NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	item, _ := NewOrderItem(product, 1)
+	order, _ := NewOrder(entityPkg.NewID(), []OrderItem{*item})
+	actorID := entityPkg.NewID()
+
+	_, err := order.Transition(OrderShipped, &actorID, "")
+	assert.Equal(t, ErrInvalidTransition, err)
+
+	_, err = order.Transition("lost", &actorID, "")
+	assert.Equal(t, ErrInvalidOrderStatus, err)
+
+	transition, err := order.Transition(OrderPaid, &actorID, "pix")
+	assert.NoError(t, err)
+	assert.Equal(t, OrderPending, transition.From)
+	assert.Equal(t, OrderPaid, transition.To)
+	assert.Equal(t, OrderPaid, order.Status)
+	assert.Len(t, order.Transitions, 2)
+	assert.False(t, transition.Restocks())
+
+	transition, err = order.Transition(OrderRefunded, &actorID, "")
+	assert.NoError(t, err)
+	assert.True(t, transition.Restocks())
+
+	// Pedidos reembolsados são finais
+	_, err = order.Transition(OrderPaid, &actorID, "")
+	assert.Equal(t, ErrInvalidTransition, err)
+}
+
+func TestOrderTransitionRestocks(t *testing.T) {
+	assert.True(t, (&OrderTransition{From: OrderPending, To: OrderCancelled}).Restocks())
+	assert.True(t, (&OrderTransition{From: OrderPaid, To: OrderCancelled}).Restocks())
+	assert.False(t, (&OrderTransition{From: OrderDelivered, To: OrderRefunded}).Restocks())
+	assert.False(t, (&OrderTransition{From: OrderShipped, To: OrderDelivered}).Restocks())
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrPasswordResetTokenExpired = errors.New("Password reset token expired")
+	ErrPasswordResetTokenUsed    = errors.New("Password reset token already used")
+)
+
+// PasswordResetToken lets a user who forgot the password choose a new one.
+// It is sent by email, works once and only the hash is stored.
+type PasswordResetToken struct {
+	ID        entity.ID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    entity.ID  `json:"user_id" gorm:"type:char(36);index"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewPasswordResetToken creates a token for the user and returns it together
+// with the plain value that goes in the email.
+func NewPasswordResetToken(userID entity.ID, ttl time.Duration) (*PasswordResetToken, string, error) {
+	plain, err := entity.NewRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+
+	return &PasswordResetToken{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		TokenHash: entity.HashToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+// Validate checks whether the token can still be used.
+func (t *PasswordResetToken) Validate() error {
+	if t.UsedAt != nil {
+		return ErrPasswordResetTokenUsed
+	}
+
+	if time.Now().After(t.ExpiresAt) {
+		return ErrPasswordResetTokenExpired
+	}
+
+	return nil
+}
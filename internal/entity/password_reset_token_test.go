@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	userID := entityPkg.NewID()
+	token, plain, err := NewPasswordResetToken(userID, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, entityPkg.HashToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.NoError(t, token.Validate())
+}
+
+func TestPasswordResetToken_Validate(t *testing.T) {
+	token, _, _ := NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+	now := time.Now()
+	token.UsedAt = &now
+	assert.ErrorIs(t, token.Validate(), ErrPasswordResetTokenUsed)
+
+	token, _, _ = NewPasswordResetToken(entityPkg.NewID(), -time.Minute)
+	assert.ErrorIs(t, token.Validate(), ErrPasswordResetTokenExpired)
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var ErrInvalidPermissionName = errors.New("Invalid permission name")
+
+// Permissions checked by the routes. Each one is a resource and an action.
+const (
+	PermissionProductWrite  = "product:write"
+	PermissionProductPurge  = "product:purge"
+	PermissionStockRead     = "stock:read"
+	PermissionStockWrite    = "stock:write"
+	PermissionCategoryWrite = "category:write"
+	PermissionOrderRead     = "order:read"
+	PermissionOrderWrite    = "order:write"
+	PermissionUserRead      = "user:read"
+	PermissionUserWrite     = "user:write"
+	PermissionRoleRead      = "role:read"
+	PermissionRoleWrite     = "role:write"
+)
+
+var permissionName = regexp.MustCompile(`^[a-z][a-z_]*:[a-z][a-z_]*$`)
+
+// Permission is a capability that can be granted to roles, named like
+// "product:write".
+type Permission struct {
+	ID          entity.ID `json:"id" gorm:"type:char(36);primaryKey"`
+	Name        string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"type:varchar(255)"`
+}
+
+func NewPermission(name, description string) (*Permission, error) {
+	permission := &Permission{
+		ID:          entity.NewID(),
+		Name:        name,
+		Description: description,
+	}
+
+	if err := permission.Validate(); err != nil {
+		return nil, err
+	}
+
+	return permission, nil
+}
+
+func (p *Permission) Validate() error {
+	if !permissionName.MatchString(p.Name) {
+		return ErrInvalidPermissionName
+	}
+	return nil
+}
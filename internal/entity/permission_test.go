@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPermission(t *testing.T) {
+	permission, err := NewPermission("product:write", "Create and edit products")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, permission.ID)
+	assert.Equal(t, "product:write", permission.Name)
+	assert.Equal(t, "Create and edit products", permission.Description)
+}
+
+func TestNewPermissionValidatesName(t *testing.T) {
+	for _, name := range []string{"", "product", "product:", ":write", "Product:Write", "product:write:all", "product write"} {
+		_, err := NewPermission(name, "")
+		assert.ErrorIs(t, err, ErrInvalidPermissionName, name)
+	}
+
+	_, err := NewPermission("low_stock:read", "")
+	assert.NoError(t, err)
+}
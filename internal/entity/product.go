@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
 )
 
 var (
@@ -13,21 +14,37 @@ var (
 	ErrNameIsRequired  = errors.New("Name is required")
 	ErrPriceIsRequired = errors.New("Price is required")
 	ErrInvalidPrice    = errors.New("Invalid price")
+	ErrInvalidLowStock = errors.New("Invalid low stock threshold")
+
+	ErrProductVersionConflict = errors.New("Product was changed by another request")
 )
 
 type Product struct {
-	ID        entity.ID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        entity.ID    `json:"id" gorm:"type:char(36);primaryKey"`
+	Name      string       `json:"name"`
+	Price     entity.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	CreatedAt time.Time    `json:"created_at"`
+
+	Stock             int `json:"stock" gorm:"not null;default:0"`
+	LowStockThreshold int `json:"low_stock_threshold" gorm:"not null;default:0"`
+
+	Categories []Category `json:"categories,omitempty" gorm:"many2many:product_categories"`
+
+	// Version muda a cada alteração do produto e serve de ETag
+	Version int64 `json:"version" gorm:"not null;default:1"`
+
+	// Produtos removidos ficam na lixeira até serem restaurados ou expurgados
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
+	DeletedBy *entity.ID     `json:"deleted_by" gorm:"type:char(36)"`
 }
 
-func NewProduct(name string, price float64) (*Product, error) {
+func NewProduct(name string, price entity.Money) (*Product, error) {
 	product := &Product{
 		ID:        entity.NewID(),
 		Name:      name,
 		Price:     price,
 		CreatedAt: time.Now(),
+		Version:   1,
 	}
 
 	err := product.ValidateProduct()
@@ -52,13 +69,32 @@ func (p *Product) ValidateProduct() error {
 		return ErrNameIsRequired
 	}
 
-	if p.Price == 0 {
+	if p.Price.IsZero() {
 		return ErrPriceIsRequired
 	}
 
-	if p.Price < 0 {
+	if p.Price.IsNegative() {
 		return ErrInvalidPrice
 	}
 
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+
+	if p.LowStockThreshold < 0 {
+		return ErrInvalidLowStock
+	}
+
 	return nil
 }
+
+// IsLowStock reports whether the stock reached the product threshold. A zero
+// threshold disables the alert.
+func (p *Product) IsLowStock() bool {
+	return p.LowStockThreshold > 0 && p.Stock <= p.LowStockThreshold
+}
+
+// IsDeleted reports whether the product is in the trash.
+func (p *Product) IsDeleted() bool {
+	return p.DeletedAt.Valid
+}
@@ -3,37 +3,64 @@ package entity
 import (
 	"testing"
 
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewProduct(t *testing.T) {
-	product, err := NewProduct("pruduct", 9.99)
+	product, err := NewProduct("pruduct", entityPkg.MustParseMoney("9.99", "BRL"))
 	assert.Nil(t, err)
 	assert.NotNil(t, product)
 	assert.NotEmpty(t, product.ID)
 	assert.Equal(t, product.Name, "pruduct")
-	assert.Equal(t, product.Price, 9.99)
+	assert.Equal(t, product.Price, entityPkg.MustParseMoney("9.99", "BRL"))
 
 }
 
 func TestProductWhenNameIsRequired(t *testing.T) {
-	product, err := NewProduct("", 9.99)
+	product, err := NewProduct("", entityPkg.MustParseMoney("9.99", "BRL"))
 	assert.Nil(t, product)
 	assert.Equal(t, err, ErrNameIsRequired)
 }
 func TestProductWhenPriceIsRequired(t *testing.T) {
-	product, err := NewProduct("product", 0.00)
+	product, err := NewProduct("product", entityPkg.MustParseMoney("0.00", "BRL"))
 	assert.Nil(t, product)
 	assert.Equal(t, err, ErrPriceIsRequired)
 }
 func TestProductWhenInvalidPrice(t *testing.T) {
-	product, err := NewProduct("product", -1.1)
+	product, err := NewProduct("product", entityPkg.MustParseMoney("-1.1", "BRL"))
 	assert.Nil(t, product)
 	assert.Equal(t, err, ErrInvalidPrice)
 }
 func TestProductValidate(t *testing.T) {
-	product, err := NewProduct("product", 1.1)
+	product, err := NewProduct("product", entityPkg.MustParseMoney("1.1", "BRL"))
 	assert.Nil(t, err)
 	assert.NotNil(t, product)
 	assert.Nil(t, product.ValidateProduct())
 }
+
+func TestProductIsLowStock(t *testing.T) {
+	product, err := NewProduct("product", entityPkg.MustParseMoney("1.1", "BRL"))
+	assert.Nil(t, err)
+	assert.False(t, product.IsLowStock())
+
+	product.LowStockThreshold = 5
+	product.Stock = 6
+	assert.False(t, product.IsLowStock())
+
+	product.Stock = 5
+	assert.True(t, product.IsLowStock())
+}
+
+func TestProductWhenInvalidLowStockThreshold(t *testing.T) {
+	product, err := NewProduct("product", entityPkg.MustParseMoney("1.1", "BRL"))
+	assert.Nil(t, err)
+	product.LowStockThreshold = -1
+	assert.Equal(t, ErrInvalidLowStock, product.ValidateProduct())
+}
+
+func TestProductWhenInvalidCurrency(t *testing.T) {
+	product, err := NewProduct("product", entityPkg.NewMoney(110, "XXX"))
+	assert.Nil(t, product)
+	assert.Equal(t, entityPkg.ErrInvalidCurrency, err)
+}
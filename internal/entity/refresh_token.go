@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrRefreshTokenExpired = errors.New("Refresh token expired")
+	ErrRefreshTokenRevoked = errors.New("Refresh token revoked")
+	ErrRefreshTokenReused  = errors.New("Refresh token reused")
+)
+
+// RefreshToken is a single-use token that can be exchanged for a new access
+// token. Every rotation creates a new token inside the same family, so reusing
+// an already rotated token lets us revoke the whole chain.
+type RefreshToken struct {
+	ID        entity.ID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    entity.ID  `json:"user_id" gorm:"type:char(36);index"`
+	FamilyID  entity.ID  `json:"family_id" gorm:"type:char(36);index"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewRefreshToken creates a token for the given family and returns it
+// together with the plain value that must be sent to the client. Only the
+// hash is persisted.
+func NewRefreshToken(userID, familyID entity.ID, ttl time.Duration) (*RefreshToken, string, error) {
+	plain, err := entity.NewRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+
+	return &RefreshToken{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: entity.HashToken(plain),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, plain, nil
+}
+
+// Validate checks whether the token can still be exchanged.
+func (t *RefreshToken) Validate() error {
+	if t.RevokedAt != nil {
+		return ErrRefreshTokenRevoked
+	}
+
+	if t.UsedAt != nil {
+		return ErrRefreshTokenReused
+	}
+
+	if time.Now().After(t.ExpiresAt) {
+		return ErrRefreshTokenExpired
+	}
+
+	return nil
+}
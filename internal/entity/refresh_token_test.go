@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	userID := entityPkg.NewID()
+	familyID := entityPkg.NewID()
+
+	token, plain, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, familyID, token.FamilyID)
+	assert.Equal(t, entityPkg.HashToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.Nil(t, token.Validate())
+}
+
+func TestRefreshTokenValidate(t *testing.T) {
+	token, _, err := NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	token.UsedAt = &now
+	assert.Equal(t, ErrRefreshTokenReused, token.Validate())
+
+	token.RevokedAt = &now
+	assert.Equal(t, ErrRefreshTokenRevoked, token.Validate())
+
+	expired, _, err := NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), -time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrRefreshTokenExpired, expired.Validate())
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+// RevokedToken marks an access token (identified by its jti claim) as no
+// longer valid even though it has not expired yet.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"type:char(36);primaryKey"`
+	UserID    entity.ID `json:"user_id" gorm:"type:char(36);index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// UserTokenRevocation invalidates every token of a user issued before
+// RevokedBefore, e.g. after a password change or a "logout all sessions".
+type UserTokenRevocation struct {
+	UserID        entity.ID `json:"user_id" gorm:"type:char(36);primaryKey"`
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+func NewRevokedToken(jti string, userID entity.ID, expiresAt time.Time) *RevokedToken {
+	return &RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		RevokedAt: time.Now(),
+	}
+}
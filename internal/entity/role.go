@@ -1,17 +1,67 @@
 package entity
 
-import "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+import (
+	"errors"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleManager  = "manager"
+	RoleCustomer = "customer"
+)
+
+var (
+	ErrBuiltInRole     = errors.New("Built-in roles can't be renamed or deleted")
+	ErrAdminRoleLocked = errors.New("The admin role always has every permission")
+	ErrRoleInUse       = errors.New("Role still has users")
+	ErrRoleNameIsTaken = errors.New("Role name is already used")
+)
 
 type Role struct {
-	ID    entity.ID `json:"id" gorm:"type:char(36);primaryKey"`
-	Name  string    `json:"name" gorm:"unique;not null"`
-	Users []User    `json:"users" gorm:"foreignKey:RoleID"`
+	ID          entity.ID    `json:"id" gorm:"type:char(36);primaryKey"`
+	Name        string       `json:"name" gorm:"unique;not null"`
+	Users       []User       `json:"users,omitempty" gorm:"foreignKey:RoleID"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+	// RequireMFA keeps the users of the role out of the permission protected
+	// routes until they log in with two-factor authentication.
+	RequireMFA bool `json:"require_mfa" gorm:"not null;default:false"`
 }
 
 func NewRole(name string) (*Role, error) {
+	if name == "" {
+		return nil, ErrNameIsRequired
+	}
 
 	return &Role{
 		ID:   entity.NewID(),
 		Name: name,
 	}, nil
 }
+
+// IsBuiltIn reports whether the role is one the code relies on: new users
+// become customers and admins keep every permission.
+func (r *Role) IsBuiltIn() bool {
+	return r.Name == RoleAdmin || r.Name == RoleManager || r.Name == RoleCustomer
+}
+
+func (r *Role) Rename(name string) error {
+	if r.IsBuiltIn() {
+		return ErrBuiltInRole
+	}
+	if name == "" {
+		return ErrNameIsRequired
+	}
+	r.Name = name
+	return nil
+}
+
+// CanChangePermissions protects the admin role, so admins can't lock
+// themselves out of the role management.
+func (r *Role) CanChangePermissions() error {
+	if r.Name == RoleAdmin {
+		return ErrAdminRoleLocked
+	}
+	return nil
+}
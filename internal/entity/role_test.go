@@ -13,3 +13,28 @@ func TestNewRole(t *testing.T) {
 	assert.NotEmpty(t, role.ID)
 	assert.Equal(t, "manager", role.Name)
 }
+
+func TestNewRoleRequiresName(t *testing.T) {
+	role, err := NewRole("")
+	assert.Nil(t, role)
+	assert.ErrorIs(t, err, ErrNameIsRequired)
+}
+
+func TestRole_Rename(t *testing.T) {
+	role, _ := NewRole("support")
+	assert.NoError(t, role.Rename("helpdesk"))
+	assert.Equal(t, "helpdesk", role.Name)
+	assert.ErrorIs(t, role.Rename(""), ErrNameIsRequired)
+
+	customer, _ := NewRole(RoleCustomer)
+	assert.ErrorIs(t, customer.Rename("client"), ErrBuiltInRole)
+	assert.Equal(t, RoleCustomer, customer.Name)
+}
+
+func TestRole_CanChangePermissions(t *testing.T) {
+	manager, _ := NewRole(RoleManager)
+	assert.NoError(t, manager.CanChangePermissions())
+
+	admin, _ := NewRole(RoleAdmin)
+	assert.ErrorIs(t, admin.CanChangePermissions(), ErrAdminRoleLocked)
+}
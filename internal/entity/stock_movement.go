@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrInvalidMovementType = errors.New("Invalid stock movement type")
+	ErrInvalidQuantity     = errors.New("Invalid quantity")
+	ErrReasonIsRequired    = errors.New("Reason is required")
+	ErrInsufficientStock   = errors.New("Insufficient stock")
+)
+
+type StockMovementType string
+
+const (
+	StockMovementReceipt    StockMovementType = "receipt"
+	StockMovementSale       StockMovementType = "sale"
+	StockMovementAdjustment StockMovementType = "adjustment"
+	StockMovementReturn     StockMovementType = "return"
+)
+
+// StockMovement is an entry of the append-only stock ledger. Quantity is the
+// signed change applied to the product stock and BalanceAfter the stock right
+// after the movement.
+type StockMovement struct {
+	ID           entity.ID         `json:"id" gorm:"type:char(36);primaryKey"`
+	ProductID    entity.ID         `json:"product_id" gorm:"type:char(36);index"`
+	Type         StockMovementType `json:"type" gorm:"type:varchar(20);not null"`
+	Quantity     int               `json:"quantity" gorm:"not null"`
+	BalanceAfter int               `json:"balance_after" gorm:"not null"`
+	Reason       string            `json:"reason" gorm:"type:varchar(255)"`
+	ActorID      *entity.ID        `json:"actor_id" gorm:"type:char(36);index"`
+	CreatedAt    time.Time         `json:"created_at" gorm:"index"`
+}
+
+// NewStockMovement builds a movement from the quantity the caller informed.
+// Receipts, returns and sales take a positive quantity and the sign comes
+// from the type; adjustments take the signed difference directly.
+func NewStockMovement(productID entity.ID, movementType StockMovementType, quantity int, reason string, actorID *entity.ID) (*StockMovement, error) {
+	delta := quantity
+
+	switch movementType {
+	case StockMovementReceipt, StockMovementReturn:
+		if quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+	case StockMovementSale:
+		if quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+		delta = -quantity
+	case StockMovementAdjustment:
+		if quantity == 0 {
+			return nil, ErrInvalidQuantity
+		}
+		if reason == "" {
+			return nil, ErrReasonIsRequired
+		}
+	default:
+		return nil, ErrInvalidMovementType
+	}
+
+	return &StockMovement{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		Type:      movementType,
+		Quantity:  delta,
+		Reason:    reason,
+		ActorID:   actorID,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"testing"
+
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStockMovement(t *testing.T) {
+	productID := entityPkg.NewID()
+	actorID := entityPkg.NewID()
+
+	receipt, err := NewStockMovement(productID, StockMovementReceipt, 10, "", &actorID)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, receipt.Quantity)
+	assert.Equal(t, productID, receipt.ProductID)
+	assert.Equal(t, actorID, *receipt.ActorID)
+
+	sale, err := NewStockMovement(productID, StockMovementSale, 3, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, -3, sale.Quantity)
+
+	returned, err := NewStockMovement(productID, StockMovementReturn, 1, "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, returned.Quantity)
+
+	adjustment, err := NewStockMovement(productID, StockMovementAdjustment, -2, "broken", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, -2, adjustment.Quantity)
+}
+
+func TestStockMovementValidation(t *testing.T) {
+	productID := entityPkg.NewID()
+
+	_, err := NewStockMovement(productID, "gift", 1, "", nil)
+	assert.Equal(t, ErrInvalidMovementType, err)
+
+	_, err = NewStockMovement(productID, StockMovementReceipt, -1, "", nil)
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	_, err = NewStockMovement(productID, StockMovementSale, 0, "", nil)
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	_, err = NewStockMovement(productID, StockMovementAdjustment, 0, "recount", nil)
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	_, err = NewStockMovement(productID, StockMovementAdjustment, 5, "", nil)
+	assert.Equal(t, ErrReasonIsRequired, err)
+}
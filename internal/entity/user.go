@@ -1,10 +1,31 @@
 package entity
 
 import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
 	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/totp"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrUserDisabled          = errors.New("User is disabled")
+	ErrPasswordResetRequired = errors.New("Password reset required")
+	ErrEmailNotVerified      = errors.New("Email is not verified")
+	ErrMFAAlreadyEnabled     = errors.New("Two-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("Two-factor authentication is not enabled")
+	ErrMFASetupNotStarted    = errors.New("Two-factor authentication setup was not started")
+	ErrMFARequiredByRole     = errors.New("Two-factor authentication is required by the role")
+	ErrInvalidMFACode        = errors.New("Invalid two-factor authentication code")
+	ErrPasswordTooShort      = errors.New("Password must have at least 8 characters")
+)
+
+// MinPasswordLength is the password policy, the same for new users, profile
+// changes and resets. Existing passwords are only checked when they change.
+const MinPasswordLength = 8
+
 type User struct {
 	ID       entity.ID `json:"id" gorm:"type:char(36);primaryKey"`
 	Name     string    `json:"name"`
@@ -13,10 +34,32 @@ type User struct {
 
 	RoleID entity.ID `json:"role_id" gorm:"type:char(36);index"`
 	Role   Role      `json:"role" gorm:"foreignKey:RoleID"`
+
+	// Disabled accounts can't log in or refresh their tokens.
+	Disabled bool `json:"disabled" gorm:"not null;default:false"`
+	// PasswordResetRequired blocks the login until the password is changed.
+	PasswordResetRequired bool `json:"password_reset_required" gorm:"not null;default:false"`
+	// EmailVerifiedAt is when the user proved to own the email, nil while it
+	// is unverified.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// MFAEnabled asks for a TOTP or recovery code after the password.
+	MFAEnabled bool `json:"mfa_enabled" gorm:"not null;default:false"`
+	// TOTPSecret is shared with the authenticator app. It is stored by the
+	// setup and only used once MFAEnabled is confirmed.
+	TOTPSecret string `json:"-" gorm:"type:varchar(64)"`
+	// TOTPLastStep is the time step of the last accepted code, so it can't be
+	// used again.
+	TOTPLastStep int64 `json:"-" gorm:"not null;default:0"`
+
+	// FailedLogins counts the wrong passwords in a row, and LockedUntil
+	// blocks the login until then. See LoginLockout.
+	FailedLogins int        `json:"failed_logins" gorm:"not null;default:0"`
+	LockedUntil  *time.Time `json:"locked_until"`
 }
 
 func NewUser(name, email, password string, roleID entity.ID) (*User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
@@ -25,12 +68,124 @@ func NewUser(name, email, password string, roleID entity.ID) (*User, error) {
 		ID:       entity.NewID(),
 		Name:     name,
 		Email:    email,
-		Password: string(hash),
+		Password: hash,
 		RoleID:   roleID,
 	}, nil
 }
 
+// hashPassword checks the password against the policy and hashes it.
+func hashPassword(password string) (string, error) {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	return entity.HashPassword(password)
+}
+
+// ChangePassword stores the hash of the new password, which also fulfils a
+// required password reset.
+func (u *User) ChangePassword(password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.Password = hash
+	u.PasswordResetRequired = false
+	return nil
+}
+
+// ChangeEmail sets a new email, which has to be verified again.
+func (u *User) ChangeEmail(email string) {
+	if email == u.Email {
+		return
+	}
+	u.Email = email
+	u.EmailVerifiedAt = nil
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// VerifyEmail marks the current email as verified, keeping the time of an
+// earlier verification.
+func (u *User) VerifyEmail(at time.Time) {
+	if u.EmailVerifiedAt == nil {
+		u.EmailVerifiedAt = &at
+	}
+}
+
+// StartMFASetup stores a new TOTP secret, which takes effect once EnableMFA
+// confirms the authenticator app has it.
+func (u *User) StartMFASetup(secret string) error {
+	if u.MFAEnabled {
+		return ErrMFAAlreadyEnabled
+	}
+	u.TOTPSecret = secret
+	u.TOTPLastStep = 0
+	return nil
+}
+
+// EnableMFA finishes the setup with a code of the authenticator app.
+func (u *User) EnableMFA(code string, now time.Time) error {
+	if u.MFAEnabled {
+		return ErrMFAAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return ErrMFASetupNotStarted
+	}
+	if !u.ValidateTOTP(code, now) {
+		return ErrInvalidMFACode
+	}
+	u.MFAEnabled = true
+	return nil
+}
+
+func (u *User) DisableMFA() {
+	u.MFAEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+}
+
+// ValidateTOTP checks a code of the authenticator app and remembers its time
+// step, so the same code is rejected afterwards.
+func (u *User) ValidateTOTP(code string, now time.Time) bool {
+	if u.TOTPSecret == "" {
+		return false
+	}
+	step, ok := totp.Validate(u.TOTPSecret, code, now, u.TOTPLastStep)
+	if ok {
+		u.TOTPLastStep = step
+	}
+	return ok
+}
+
 func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// LoginLockedFor returns how long the login is still locked after failed
+// attempts, zero when it isn't.
+func (u *User) LoginLockedFor(now time.Time) time.Duration {
+	if u.LockedUntil == nil || !u.LockedUntil.After(now) {
+		return 0
+	}
+	return u.LockedUntil.Sub(now)
+}
+
+// Unlock forgets the failed logins, lifting the lock.
+func (u *User) Unlock() {
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+}
+
+// CanLogin reports why the user is not allowed to get new tokens, if any.
+func (u *User) CanLogin() error {
+	if u.Disabled {
+		return ErrUserDisabled
+	}
+	if u.PasswordResetRequired {
+		return ErrPasswordResetRequired
+	}
+	return nil
+}
@@ -2,13 +2,16 @@ package entity
 
 import (
 	"testing"
+	"time"
 
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/totp"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewUser(t *testing.T) {
-	roleID := NewID()
-	user, err := NewUser("Mateus", "m.m@gmail.com", "123456", roleID)
+	roleID := entityPkg.NewID()
+	user, err := NewUser("Mateus", "m.m@gmail.com", "12345678", roleID)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.NotEmpty(t, user.ID)
@@ -16,14 +19,111 @@ func TestNewUser(t *testing.T) {
 	assert.Equal(t, "Mateus", user.Name)
 	assert.Equal(t, "m.m@gmail.com", user.Email)
 	assert.Equal(t, roleID, user.RoleID)
-	assert.NotEqual(t, "123456", user.Password)
+	assert.NotEqual(t, "12345678", user.Password)
 }
 
 func TestUser_ValidatePassword(t *testing.T) {
-	roleID := NewID()
-	user, err := NewUser("Mateus", "m.m@gmail.com", "123456", roleID)
+	roleID := entityPkg.NewID()
+	user, err := NewUser("Mateus", "m.m@gmail.com", "12345678", roleID)
 	assert.NoError(t, err)
-	assert.True(t, user.ValidatePassword("123456"))
-	assert.False(t, user.ValidatePassword("1234567"))
-	assert.NotEqual(t, "123456", user.Password)
+	assert.True(t, user.ValidatePassword("12345678"))
+	assert.False(t, user.ValidatePassword("123456789"))
+	assert.NotEqual(t, "12345678", user.Password)
+}
+
+func TestUser_CanLogin(t *testing.T) {
+	user, err := NewUser("Mateus", "m.m@gmail.com", "12345678", entityPkg.NewID())
+	assert.NoError(t, err)
+	assert.NoError(t, user.CanLogin())
+
+	user.PasswordResetRequired = true
+	assert.ErrorIs(t, user.CanLogin(), ErrPasswordResetRequired)
+
+	user.Disabled = true
+	assert.ErrorIs(t, user.CanLogin(), ErrUserDisabled)
+}
+
+func TestUser_PasswordPolicy(t *testing.T) {
+	_, err := NewUser("Mateus", "m.m@gmail.com", "1234567", entityPkg.NewID())
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+
+	// Conta caracteres, não bytes
+	user, err := NewUser("Mateus", "m.m@gmail.com", "ãããããããã", entityPkg.NewID())
+	assert.NoError(t, err)
+	assert.ErrorIs(t, user.ChangePassword("ããããããã"), ErrPasswordTooShort)
+}
+
+func TestUser_ChangePassword(t *testing.T) {
+	user, err := NewUser("Mateus", "m.m@gmail.com", "12345678", entityPkg.NewID())
+	assert.NoError(t, err)
+	user.PasswordResetRequired = true
+
+	assert.NoError(t, user.ChangePassword("new-password"))
+	assert.True(t, user.ValidatePassword("new-password"))
+	assert.False(t, user.ValidatePassword("12345678"))
+	assert.False(t, user.PasswordResetRequired)
+}
+
+func TestUser_VerifyEmail(t *testing.T) {
+	user, err := NewUser("Mateus", "m.m@gmail.com", "12345678", entityPkg.NewID())
+	assert.NoError(t, err)
+	assert.False(t, user.EmailVerified())
+
+	verifiedAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	user.VerifyEmail(verifiedAt)
+	user.VerifyEmail(verifiedAt.Add(time.Hour))
+	assert.True(t, user.EmailVerified())
+	assert.Equal(t, verifiedAt, *user.EmailVerifiedAt)
+
+	// O mesmo email continua verificado, um novo precisa ser verificado
+	user.ChangeEmail("m.m@gmail.com")
+	assert.True(t, user.EmailVerified())
+	user.ChangeEmail("mateus@example.com")
+	assert.Equal(t, "mateus@example.com", user.Email)
+	assert.False(t, user.EmailVerified())
+}
+
+func TestUser_MFA(t *testing.T) {
+	user, err := NewUser("Mateus", "m.m@gmail.com", "12345678", entityPkg.NewID())
+	assert.NoError(t, err)
+	now := time.Now()
+
+	assert.ErrorIs(t, user.EnableMFA("123456", now), ErrMFASetupNotStarted)
+
+	secret, err := totp.NewSecret()
+	assert.NoError(t, err)
+	assert.NoError(t, user.StartMFASetup(secret))
+	assert.ErrorIs(t, user.EnableMFA("000000", now.Add(-time.Hour)), ErrInvalidMFACode)
+	assert.False(t, user.MFAEnabled)
+
+	code, _ := totp.Code(secret, totp.Step(now))
+	assert.NoError(t, user.EnableMFA(code, now))
+	assert.True(t, user.MFAEnabled)
+	assert.ErrorIs(t, user.StartMFASetup(secret), ErrMFAAlreadyEnabled)
+
+	// O código usado na confirmação não vale para o login
+	assert.False(t, user.ValidateTOTP(code, now))
+	next, _ := totp.Code(secret, totp.Step(now)+1)
+	assert.True(t, user.ValidateTOTP(next, now))
+
+	user.DisableMFA()
+	assert.False(t, user.MFAEnabled)
+	assert.Empty(t, user.TOTPSecret)
+	assert.False(t, user.ValidateTOTP(next, now))
+}
+
+func TestUser_LoginLock(t *testing.T) {
+	user, _ := NewUser("John Doe", "j@j.com", "12345678", entityPkg.NewID())
+	now := time.Now()
+	assert.Zero(t, user.LoginLockedFor(now))
+
+	until := now.Add(time.Minute)
+	user.FailedLogins = 10
+	user.LockedUntil = &until
+	assert.Equal(t, time.Minute, user.LoginLockedFor(now))
+	assert.Zero(t, user.LoginLockedFor(until))
+
+	user.Unlock()
+	assert.Zero(t, user.FailedLogins)
+	assert.Nil(t, user.LockedUntil)
 }
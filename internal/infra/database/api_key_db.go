@@ -0,0 +1,86 @@
+package database
+
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval is how stale last_used_at may get, so a busy key
+// isn't written on every request.
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyDB struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyDB(db *gorm.DB) *APIKeyDB {
+	return &APIKeyDB{
+		DB: db,
+	}
+}
+
+// CreateAPIKey stores the key and links it to its scopes, which must
+// already exist.
+func (adb *APIKeyDB) CreateAPIKey(key *entity.APIKey) error {
+	return adb.DB.Omit("Scopes.*").Create(key).Error
+}
+
+func (adb *APIKeyDB) FindAPIKeyByHash(hash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := adb.DB.Preload("Scopes", orderPermissions).Where("key_hash = ?", hash).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindUserAPIKey returns a key of the user that wasn't revoked. Keys of
+// other users are not found.
+func (adb *APIKeyDB) FindUserAPIKey(userID, id string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := adb.DB.Preload("Scopes", orderPermissions).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindUserAPIKeys returns the keys of the user that weren't revoked, newest
+// first.
+func (adb *APIKeyDB) FindUserAPIKeys(userID string) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	err := adb.DB.Preload("Scopes", orderPermissions).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+func (adb *APIKeyDB) RenameAPIKey(key *entity.APIKey) error {
+	return adb.DB.Model(key).Update("name", key.Name).Error
+}
+
+// RevokeAPIKey revokes a key of the user, returning false when the user has
+// no such key or it was already revoked.
+func (adb *APIKeyDB) RevokeAPIKey(userID, id string) (bool, error) {
+	result := adb.DB.Model(&entity.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// TouchAPIKey records that the key was used, at most once a minute.
+func (adb *APIKeyDB) TouchAPIKey(key *entity.APIKey, at time.Time) error {
+	if key.LastUsedAt != nil && at.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+	key.LastUsedAt = &at
+	return adb.DB.Model(&entity.APIKey{}).Where("id = ?", key.ID).Update("last_used_at", at).Error
+}
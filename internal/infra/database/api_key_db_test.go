@@ -0,0 +1,100 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupAPIKeyDB(t *testing.T) (*gorm.DB, *APIKeyDB) {
+	db := newTestDB(t, &entity.Permission{}, &entity.APIKey{})
+	return db, NewAPIKeyDB(db)
+}
+
+func TestCreateAndFindAPIKey(t *testing.T) {
+	db, keyDB := setupAPIKeyDB(t)
+	write, _ := entity.NewPermission(entity.PermissionProductWrite, "")
+	read, _ := entity.NewPermission(entity.PermissionStockRead, "")
+	db.Create(write)
+	db.Create(read)
+
+	userID := entityPkg.NewID()
+	key, plain, err := entity.NewAPIKey(userID, "deploy", []entity.Permission{*write, *read}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, keyDB.CreateAPIKey(key))
+
+	hash, _ := entity.HashAPIKey(plain)
+	found, err := keyDB.FindAPIKeyByHash(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, []string{entity.PermissionProductWrite, entity.PermissionStockRead}, found.ScopeNames())
+
+	// Os escopos não criam nem alteram permissões
+	var permissions int64
+	db.Model(&entity.Permission{}).Count(&permissions)
+	assert.Equal(t, int64(2), permissions)
+
+	_, err = keyDB.FindUserAPIKey(entityPkg.NewID().String(), key.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	key.Name = "ci"
+	assert.NoError(t, keyDB.RenameAPIKey(key))
+	found, err = keyDB.FindUserAPIKey(userID.String(), key.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", found.Name)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	_, keyDB := setupAPIKeyDB(t)
+	userID := entityPkg.NewID()
+	key, plain, _ := entity.NewAPIKey(userID, "deploy", nil, nil)
+	other, _, _ := entity.NewAPIKey(userID, "backup", nil, nil)
+	assert.NoError(t, keyDB.CreateAPIKey(key))
+	assert.NoError(t, keyDB.CreateAPIKey(other))
+
+	revoked, err := keyDB.RevokeAPIKey(entityPkg.NewID().String(), key.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = keyDB.RevokeAPIKey(userID.String(), key.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = keyDB.RevokeAPIKey(userID.String(), key.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	keys, err := keyDB.FindUserAPIKeys(userID.String())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, other.ID, keys[0].ID)
+
+	// A chave continua sendo encontrada pelo hash, para dizer que foi revogada
+	hash, _ := entity.HashAPIKey(plain)
+	found, err := keyDB.FindAPIKeyByHash(hash)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, found.Validate(), entity.ErrAPIKeyRevoked)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	db, keyDB := setupAPIKeyDB(t)
+	key, _, _ := entity.NewAPIKey(entityPkg.NewID(), "deploy", nil, nil)
+	assert.NoError(t, keyDB.CreateAPIKey(key))
+
+	first := time.Now()
+	assert.NoError(t, keyDB.TouchAPIKey(key, first))
+	assert.NoError(t, keyDB.TouchAPIKey(key, first.Add(10*time.Second)))
+
+	var stored entity.APIKey
+	db.First(&stored, "id = ?", key.ID)
+	assert.WithinDuration(t, first, *stored.LastUsedAt, time.Millisecond)
+
+	later := first.Add(2 * time.Minute)
+	assert.NoError(t, keyDB.TouchAPIKey(key, later))
+	db.First(&stored, "id = ?", key.ID)
+	assert.WithinDuration(t, later, *stored.LastUsedAt, time.Millisecond)
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartDB struct {
+	DB *gorm.DB
+}
+
+func NewCartDB(db *gorm.DB) *CartDB {
+	return &CartDB{
+		DB: db,
+	}
+}
+
+// FindOrCreateCart returns the user's cart with its items, creating an empty
+// cart on first access.
+func (cdb *CartDB) FindOrCreateCart(userID string) (*entity.Cart, error) {
+	id, err := entityPkg.ParseID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cart entity.Cart
+	err = cdb.DB.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("added_at asc") }).
+		Where("user_id = ?", userID).
+		First(&cart).
+		Error
+	if err == nil {
+		return &cart, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	newCart := entity.NewCart(id)
+	// Outra requisição pode ter criado o carrinho ao mesmo tempo
+	err = cdb.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(newCart).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = cdb.DB.Preload("Items").Where("user_id = ?", userID).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// AddCartItem inserts the item or, when the product is already in the cart,
+// adds the quantity to the existing line keeping its original price.
+func (cdb *CartDB) AddCartItem(item *entity.CartItem) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "cart_id"}, {Name: "product_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"quantity": gorm.Expr("cart_items.quantity + ?", item.Quantity),
+			}),
+		}).Create(item).Error
+		if err != nil {
+			return err
+		}
+		return touchCart(tx, item.CartID.String())
+	})
+}
+
+func (cdb *CartDB) UpdateCartItemQuantity(cartID, productID string, quantity int) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.CartItem{}).
+			Where("cart_id = ? AND product_id = ?", cartID, productID).
+			Update("quantity", quantity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrCartItemNotFound
+		}
+		return touchCart(tx, cartID)
+	})
+}
+
+func (cdb *CartDB) RemoveCartItem(cartID, productID string) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("cart_id = ? AND product_id = ?", cartID, productID).Delete(&entity.CartItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrCartItemNotFound
+		}
+		return touchCart(tx, cartID)
+	})
+}
+
+func (cdb *CartDB) ClearCart(cartID string) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id = ?", cartID).Delete(&entity.CartItem{}).Error; err != nil {
+			return err
+		}
+		return touchCart(tx, cartID)
+	})
+}
+
+func touchCart(tx *gorm.DB, cartID string) error {
+	return tx.Model(&entity.Cart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+}
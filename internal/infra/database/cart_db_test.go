@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupCartDB(t *testing.T) (*gorm.DB, *CartDB) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{}, &entity.Cart{}, &entity.CartItem{})
+	return db, NewCartDB(db)
+}
+
+func TestFindOrCreateCart(t *testing.T) {
+	_, cartDB := setupCartDB(t)
+	userID := entityPkg.NewID().String()
+
+	cart, err := cartDB.FindOrCreateCart(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, cart.UserID.String())
+	assert.Empty(t, cart.Items)
+
+	again, err := cartDB.FindOrCreateCart(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, cart.ID, again.ID)
+}
+
+func TestAddCartItemMergesQuantities(t *testing.T) {
+	_, cartDB := setupCartDB(t)
+	cart, _ := cartDB.FindOrCreateCart(entityPkg.NewID().String())
+	product, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+
+	item, _ := entity.NewCartItem(cart.ID, product, 2)
+	assert.NoError(t, cartDB.AddCartItem(item))
+
+	// Mesmo produto com outro preço: soma a quantidade e mantém o preço original
+	product.Price = entityPkg.MustParseMoney("12.99", "BRL")
+	item, _ = entity.NewCartItem(cart.ID, product, 3)
+	assert.NoError(t, cartDB.AddCartItem(item))
+
+	cart, err := cartDB.FindOrCreateCart(cart.UserID.String())
+	assert.NoError(t, err)
+	assert.Len(t, cart.Items, 1)
+	assert.Equal(t, 5, cart.Items[0].Quantity)
+	assert.Equal(t, entityPkg.MustParseMoney("9.99", "BRL"), cart.Items[0].UnitPrice)
+}
+
+func TestUpdateAndRemoveCartItems(t *testing.T) {
+	_, cartDB := setupCartDB(t)
+	cart, _ := cartDB.FindOrCreateCart(entityPkg.NewID().String())
+	shirt, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	pants, _ := entity.NewProduct("Calça", entityPkg.MustParseMoney("19.99", "BRL"))
+
+	for _, p := range []*entity.Product{shirt, pants} {
+		item, _ := entity.NewCartItem(cart.ID, p, 1)
+		assert.NoError(t, cartDB.AddCartItem(item))
+	}
+
+	assert.NoError(t, cartDB.UpdateCartItemQuantity(cart.ID.String(), shirt.ID.String(), 4))
+	assert.Equal(t, entity.ErrCartItemNotFound, cartDB.UpdateCartItemQuantity(cart.ID.String(), entityPkg.NewID().String(), 4))
+
+	assert.NoError(t, cartDB.RemoveCartItem(cart.ID.String(), pants.ID.String()))
+	assert.Equal(t, entity.ErrCartItemNotFound, cartDB.RemoveCartItem(cart.ID.String(), pants.ID.String()))
+
+	cart, _ = cartDB.FindOrCreateCart(cart.UserID.String())
+	assert.Len(t, cart.Items, 1)
+	assert.Equal(t, 4, cart.Items[0].Quantity)
+
+	assert.NoError(t, cartDB.ClearCart(cart.ID.String()))
+	cart, _ = cartDB.FindOrCreateCart(cart.UserID.String())
+	assert.Empty(t, cart.Items)
+}
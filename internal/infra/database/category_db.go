@@ -0,0 +1,93 @@
+package database
+
+import (
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type CategoryDB struct {
+	DB *gorm.DB
+}
+
+func NewCategoryDB(db *gorm.DB) *CategoryDB {
+	return &CategoryDB{
+		DB: db,
+	}
+}
+
+func (cdb *CategoryDB) CreateCategory(category *entity.Category) error {
+	return cdb.DB.Create(category).Error
+}
+
+func (cdb *CategoryDB) FindCategoryByID(id string) (*entity.Category, error) {
+	var category entity.Category
+	err := cdb.DB.First(&category, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (cdb *CategoryDB) FindCategoriesByIDs(ids []string) ([]entity.Category, error) {
+	var categories []entity.Category
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	err := cdb.DB.Where("id IN ?", ids).Find(&categories).Error
+	return categories, err
+}
+
+func (cdb *CategoryDB) FindAllCategories() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := cdb.DB.Order("name asc").Find(&categories).Error
+	return categories, err
+}
+
+func (cdb *CategoryDB) UpdateCategory(category *entity.Category) error {
+	return cdb.DB.Save(category).Error
+}
+
+// DeleteCategory removes the category and its product links. Its children
+// are moved up to the deleted category's parent so the tree stays connected.
+func (cdb *CategoryDB) DeleteCategory(id string) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		var category entity.Category
+		if err := tx.First(&category, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&entity.Category{}).
+			Where("parent_id = ?", id).
+			Update("parent_id", category.ParentID).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&entity.Category{}, "id = ?", id).Error
+	})
+}
+
+// FindDescendantIDs returns the IDs of every category nested below id.
+func (cdb *CategoryDB) FindDescendantIDs(id string) ([]string, error) {
+	root, err := entityPkg.ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := cdb.FindAllCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, descendant := range entity.DescendantIDs(categories, root) {
+		ids = append(ids, descendant.String())
+	}
+	return ids, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupCategoryDB(t *testing.T) (*gorm.DB, *CategoryDB) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+	return db, NewCategoryDB(db)
+}
+
+func TestCreateCategory(t *testing.T) {
+	_, categoryDB := setupCategoryDB(t)
+
+	parent, _ := entity.NewCategory("Electronics", nil)
+	assert.NoError(t, categoryDB.CreateCategory(parent))
+
+	child, _ := entity.NewCategory("Audio", &parent.ID)
+	assert.NoError(t, categoryDB.CreateCategory(child))
+
+	found, err := categoryDB.FindCategoryByID(child.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, child.Name, found.Name)
+	assert.Equal(t, parent.ID, *found.ParentID)
+}
+
+func TestFindDescendantIDs(t *testing.T) {
+	_, categoryDB := setupCategoryDB(t)
+
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	audio, _ := entity.NewCategory("Audio", &electronics.ID)
+	headphones, _ := entity.NewCategory("Headphones", &audio.ID)
+	kitchen, _ := entity.NewCategory("Kitchen", nil)
+	for _, c := range []*entity.Category{electronics, audio, headphones, kitchen} {
+		assert.NoError(t, categoryDB.CreateCategory(c))
+	}
+
+	ids, err := categoryDB.FindDescendantIDs(electronics.ID.String())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{audio.ID.String(), headphones.ID.String()}, ids)
+}
+
+func TestDeleteCategoryMovesChildrenUp(t *testing.T) {
+	db, categoryDB := setupCategoryDB(t)
+
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	audio, _ := entity.NewCategory("Audio", &electronics.ID)
+	headphones, _ := entity.NewCategory("Headphones", &audio.ID)
+	for _, c := range []*entity.Category{electronics, audio, headphones} {
+		assert.NoError(t, categoryDB.CreateCategory(c))
+	}
+
+	product, _ := entity.NewProduct("Fone", entityPkg.MustParseMoney("99.9", "BRL"))
+	product.Categories = []entity.Category{*audio}
+	assert.NoError(t, db.Create(product).Error)
+
+	assert.NoError(t, categoryDB.DeleteCategory(audio.ID.String()))
+
+	_, err := categoryDB.FindCategoryByID(audio.ID.String())
+	assert.Error(t, err)
+
+	found, err := categoryDB.FindCategoryByID(headphones.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, electronics.ID, *found.ParentID)
+
+	var links int64
+	db.Table("product_categories").Where("category_id = ?", audio.ID).Count(&links)
+	assert.Equal(t, int64(0), links)
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigDialector(t *testing.T) {
+	for _, driver := range []string{"mysql", "postgres", "sqlite"} {
+		dialector, err := Config{Driver: driver}.Dialector()
+		assert.NoError(t, err)
+		assert.Equal(t, driver, dialector.Name())
+	}
+
+	_, err := Config{Driver: "oracle"}.Dialector()
+	assert.Error(t, err)
+}
+
+func TestNewConnectionSQLite(t *testing.T) {
+	cfg := Config{
+		Driver:          DriverSQLite,
+		Name:            filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns:    5,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Minute,
+	}
+
+	db, err := NewConnection(cfg)
+	assert.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Ping())
+	assert.Equal(t, 5, sqlDB.Stats().MaxOpenConnections)
+	sqlDB.Close()
+}
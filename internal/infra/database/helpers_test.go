@@ -0,0 +1,27 @@
+package database
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens an in-memory SQLite database with the tables of the models.
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	return openTestDB(t, "file::memory:", models...)
+}
+
+// openTestDB opens the SQLite database of the dsn with the tables of the
+// models, failing the test when it can't.
+func openTestDB(t *testing.T, dsn string, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("could not open db: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+	return db
+}
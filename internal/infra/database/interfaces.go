@@ -1,25 +1,197 @@
 package database
 
-import "github.com/mateusfaustino/go-rest-api-III/internal/entity"
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+// UserQuery describes a page of users for FindUsers, ordered by name. Empty
+// filters don't filter anything.
+type UserQuery struct {
+	Page  int
+	Limit int
+	// Search matches part of the name or the email, ignoring case.
+	Search   string
+	RoleID   string
+	Disabled *bool
+}
 
 type UserInterface interface {
 	CreateUser(user *entity.User) error
 	FindUserByEmail(email string) (*entity.User, error)
 	FindUserById(id string) (*entity.User, error)
+	FindUsers(query UserQuery) ([]entity.User, int64, error)
 	UpdateUser(user *entity.User) error
+	UpdateUserColumns(user *entity.User, columns ...string) error
+	UseTOTPStep(id string, step int64) (bool, error)
+	RecordFailedLogin(id string, lockout entity.LoginLockout) (*time.Time, error)
+	ResetFailedLogins(id string) error
+	DeleteUser(id string) error
+}
+
+// ProductSortColumns maps the fields products can be sorted by to their
+// columns. Anything else is rejected, so it's safe to build ORDER BY from it.
+var ProductSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+	"price":      "price_amount",
+	"stock":      "stock",
+}
+
+// ProductSort is a sort key of a ProductQuery.
+type ProductSort struct {
+	Field string
+	Desc  bool
+}
+
+// ProductQuery describes a page of products for FindAllProducts. Empty
+// filters don't filter anything.
+type ProductQuery struct {
+	Page  int
+	Limit int
+	// Sort keys in order of precedence. The id is always the last key, in
+	// the direction of the previous one, so pages are stable.
+	Sort []ProductSort
+	// After switches to keyset pagination: the page starts right after the
+	// cursor, in its direction, and Page and Sort are ignored.
+	After *cursor.Cursor
+
+	// CategoryIDs keeps products linked to any of the categories.
+	CategoryIDs []string
+	// InStock keeps products with (true) or without (false) stock.
+	InStock *bool
+	// PriceMin and PriceMax are inclusive and keep only products in their
+	// currency.
+	PriceMin *entityPkg.Money
+	PriceMax *entityPkg.Money
+	// NameContains matches part of the name, ignoring case.
+	NameContains  string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
 }
 
 type ProductInterface interface {
 	CreateProduct(product *entity.Product) error
-	FindAllProducts(page, limit int, sort string) ([]entity.Product, error)
+	FindAllProducts(query ProductQuery) ([]entity.Product, int64, error)
 	FindProductByID(id string) (*entity.Product, error)
-	UpdateProduct(product *entity.Product) error
-	DeleteProduct(id string) error
+	FindProductsByIDs(ids []string) ([]entity.Product, error)
+	UpdateProduct(product *entity.Product, categories []entity.Category) error
+	DeleteProduct(product *entity.Product, actorID *entityPkg.ID) error
+	FindDeletedProducts(page, limit int) ([]entity.Product, int64, error)
+	RestoreProduct(id string) (*entity.Product, error)
+	PurgeProduct(id string) error
+	PurgeDeletedProducts(before time.Time) (int64, error)
+}
+
+type StockInterface interface {
+	AdjustStock(movement *entity.StockMovement) error
+	FindStockMovements(productID string, page, limit int) ([]entity.StockMovement, error)
+	FindLowStockProducts() ([]entity.Product, error)
+}
+
+type CartInterface interface {
+	FindOrCreateCart(userID string) (*entity.Cart, error)
+	AddCartItem(item *entity.CartItem) error
+	UpdateCartItemQuantity(cartID, productID string, quantity int) error
+	RemoveCartItem(cartID, productID string) error
+	ClearCart(cartID string) error
+}
+
+// OrderFilter narrows down the orders returned by FindOrders, newest first.
+// Empty fields don't filter anything.
+type OrderFilter struct {
+	UserID string
+	Status string
+	// After switches to keyset pagination, ignoring the page number.
+	After *cursor.Cursor
+}
+
+type OrderInterface interface {
+	CreateOrder(order *entity.Order, cartItems []entity.CartItem) error
+	FindOrderByID(id string) (*entity.Order, error)
+	FindOrders(page, limit int, filter OrderFilter) ([]entity.Order, error)
+	TransitionOrder(id string, to entity.OrderStatus, actorID *entityPkg.ID, note string) (*entity.Order, error)
+}
+
+type CategoryInterface interface {
+	CreateCategory(category *entity.Category) error
+	FindCategoryByID(id string) (*entity.Category, error)
+	FindCategoriesByIDs(ids []string) ([]entity.Category, error)
+	FindAllCategories() ([]entity.Category, error)
+	UpdateCategory(category *entity.Category) error
+	DeleteCategory(id string) error
+	FindDescendantIDs(id string) ([]string, error)
 }
 
 type RoleInterface interface {
-       FindRoleByName(name string) (*entity.Role, error)
-       CreateRole(role *entity.Role) error
-       RoleExists(roleName string) (bool, error)
-       FindRoleByID(id string) (*entity.Role, error)
+	FindRoleByName(name string) (*entity.Role, error)
+	CreateRole(role *entity.Role) error
+	RoleExists(roleName string) (bool, error)
+	FindRoleByID(id string) (*entity.Role, error)
+	FindAllRoles() ([]entity.Role, error)
+	UpdateRole(role *entity.Role) error
+	DeleteRole(id string) error
+	SetRolePermissions(role *entity.Role, permissions []entity.Permission) error
+	FindRolePermissions(roleID string) ([]string, error)
+	SetRoleRequireMFA(id string, required bool) error
+	RoleRequiresMFA(roleID string) (bool, error)
+}
+
+type PermissionInterface interface {
+	CreatePermission(permission *entity.Permission) error
+	FindAllPermissions() ([]entity.Permission, error)
+	FindPermissionsByNames(names []string) ([]entity.Permission, error)
+}
+
+type RefreshTokenInterface interface {
+	CreateRefreshToken(token *entity.RefreshToken) error
+	FindRefreshTokenByHash(hash string) (*entity.RefreshToken, error)
+	MarkRefreshTokenUsed(id string) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeUserRefreshTokens(userID string) error
+}
+
+type PasswordResetTokenInterface interface {
+	CreatePasswordResetToken(token *entity.PasswordResetToken) error
+	FindPasswordResetTokenByHash(hash string) (*entity.PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(id string) (bool, error)
+	InvalidateUserPasswordResetTokens(userID string) error
+	DeleteExpiredPasswordResetTokens() error
+}
+
+type MFARecoveryCodeInterface interface {
+	ReplaceMFARecoveryCodes(userID string, codes []entity.MFARecoveryCode) error
+	UseMFARecoveryCode(userID, hash string) (bool, error)
+	DeleteMFARecoveryCodes(userID string) error
+}
+
+type APIKeyInterface interface {
+	CreateAPIKey(key *entity.APIKey) error
+	FindAPIKeyByHash(hash string) (*entity.APIKey, error)
+	FindUserAPIKey(userID, id string) (*entity.APIKey, error)
+	FindUserAPIKeys(userID string) ([]entity.APIKey, error)
+	RenameAPIKey(key *entity.APIKey) error
+	RevokeAPIKey(userID, id string) (bool, error)
+	TouchAPIKey(key *entity.APIKey, at time.Time) error
+}
+
+type LoginAttemptInterface interface {
+	CreateLoginAttempt(attempt *entity.LoginAttempt) error
+	FindUserLoginAttempts(userID string, page, limit int) ([]entity.LoginAttempt, int64, error)
+	DeleteLoginAttemptsBefore(before time.Time) error
+	FindLoginFailure(email string) (*entity.LoginFailure, error)
+	RecordLoginFailure(email string, lockout entity.LoginLockout) (*time.Time, error)
+	DeleteLoginFailuresBefore(before time.Time) error
+}
+
+type TokenRevocationInterface interface {
+	RevokeToken(token *entity.RevokedToken) error
+	ConsumeToken(token *entity.RevokedToken) (bool, error)
+	IsTokenRevoked(jti string) (bool, error)
+	RevokeUserTokens(userID string, before time.Time) error
+	FindUserTokensRevokedBefore(userID string) (time.Time, error)
+	DeleteExpiredRevokedTokens() error
 }
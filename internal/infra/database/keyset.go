@@ -0,0 +1,23 @@
+package database
+
+import (
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	"gorm.io/gorm"
+)
+
+// afterCursor keeps the rows after the cursor position in the (created_at,
+// id) order and sorts them by it. With an index on created_at the database
+// seeks straight to the position, and rows inserted meanwhile don't shift
+// the pages like an OFFSET would.
+func afterCursor(db *gorm.DB, after *cursor.Cursor) *gorm.DB {
+	if after.Desc {
+		return db.
+			Where("(created_at < ? OR (created_at = ? AND id < ?))", after.CreatedAt, after.CreatedAt, after.ID).
+			Order("created_at desc").
+			Order("id desc")
+	}
+	return db.
+		Where("(created_at > ? OR (created_at = ? AND id > ?))", after.CreatedAt, after.CreatedAt, after.ID).
+		Order("created_at").
+		Order("id")
+}
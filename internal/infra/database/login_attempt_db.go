@@ -0,0 +1,86 @@
+package database
+
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptDB struct {
+	DB *gorm.DB
+}
+
+func NewLoginAttemptDB(db *gorm.DB) *LoginAttemptDB {
+	return &LoginAttemptDB{
+		DB: db,
+	}
+}
+
+func (ldb *LoginAttemptDB) CreateLoginAttempt(attempt *entity.LoginAttempt) error {
+	return ldb.DB.Create(attempt).Error
+}
+
+// FindUserLoginAttempts returns a page of the login attempts of the user,
+// newest first, and their total.
+func (ldb *LoginAttemptDB) FindUserLoginAttempts(userID string, page, limit int) ([]entity.LoginAttempt, int64, error) {
+	db := ldb.DB.Model(&entity.LoginAttempt{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []entity.LoginAttempt
+	err := db.Order("created_at DESC").
+		Order("id").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&attempts).
+		Error
+	return attempts, total, err
+}
+
+// DeleteLoginAttemptsBefore removes the attempts older than the retention.
+func (ldb *LoginAttemptDB) DeleteLoginAttemptsBefore(before time.Time) error {
+	return ldb.DB.Where("created_at < ?", before).Delete(&entity.LoginAttempt{}).Error
+}
+
+// FindLoginFailure returns the failed logins of an email without an account.
+func (ldb *LoginAttemptDB) FindLoginFailure(email string) (*entity.LoginFailure, error) {
+	var failure entity.LoginFailure
+	err := ldb.DB.Where("email = ?", email).First(&failure).Error
+	return &failure, err
+}
+
+// RecordLoginFailure counts a failed login of an email without an account
+// and locks it as the lockout says, the same way RecordFailedLogin does for
+// accounts. It returns until when the email is locked, nil if it isn't.
+func (ldb *LoginAttemptDB) RecordLoginFailure(email string, lockout entity.LoginLockout) (*time.Time, error) {
+	var until *time.Time
+	err := ldb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"failures": gorm.Expr("failures + 1"), "updated_at": time.Now()}),
+		}).Create(&entity.LoginFailure{Email: email, Failures: 1}).Error
+		if err != nil {
+			return err
+		}
+
+		var failures int
+		if err := tx.Model(&entity.LoginFailure{}).Where("email = ?", email).Select("failures").Scan(&failures).Error; err != nil {
+			return err
+		}
+
+		until = lockout.Until(failures, time.Now())
+		return tx.Model(&entity.LoginFailure{}).Where("email = ?", email).Update("locked_until", until).Error
+	})
+	return until, err
+}
+
+// DeleteLoginFailuresBefore forgets the emails without an account that had
+// no failed login since before, so made-up emails don't pile up.
+func (ldb *LoginAttemptDB) DeleteLoginFailuresBefore(before time.Time) error {
+	return ldb.DB.Where("updated_at < ?", before).Delete(&entity.LoginFailure{}).Error
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupLoginAttemptDB(t *testing.T) (*gorm.DB, *LoginAttemptDB) {
+	db := newTestDB(t, &entity.LoginAttempt{}, &entity.LoginFailure{})
+	return db, NewLoginAttemptDB(db)
+}
+
+func TestFindUserLoginAttempts(t *testing.T) {
+	_, attemptDB := setupLoginAttemptDB(t)
+	userID := entityPkg.NewID()
+	now := time.Now()
+
+	for i, outcome := range []string{entity.LoginFailed, entity.LoginFailed, entity.LoginSucceeded} {
+		attempt := entity.NewLoginAttempt(&userID, "ana@example.com", "10.0.0.1", "curl", outcome)
+		attempt.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		assert.NoError(t, attemptDB.CreateLoginAttempt(attempt))
+	}
+	// Tentativas de outros usuários ou sem usuário não aparecem
+	other := entityPkg.NewID()
+	assert.NoError(t, attemptDB.CreateLoginAttempt(entity.NewLoginAttempt(&other, "bruno@example.com", "10.0.0.2", "curl", entity.LoginFailed)))
+	assert.NoError(t, attemptDB.CreateLoginAttempt(entity.NewLoginAttempt(nil, "nobody@example.com", "10.0.0.2", "curl", entity.LoginFailed)))
+
+	attempts, total, err := attemptDB.FindUserLoginAttempts(userID.String(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, attempts, 2)
+	assert.Equal(t, entity.LoginSucceeded, attempts[0].Outcome)
+
+	attempts, _, err = attemptDB.FindUserLoginAttempts(userID.String(), 2, 2)
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 1)
+}
+
+func TestDeleteLoginAttemptsBefore(t *testing.T) {
+	db, attemptDB := setupLoginAttemptDB(t)
+	old := entity.NewLoginAttempt(nil, "ana@example.com", "10.0.0.1", "curl", entity.LoginFailed)
+	old.CreatedAt = time.Now().Add(-48 * time.Hour)
+	recent := entity.NewLoginAttempt(nil, "ana@example.com", "10.0.0.1", "curl", entity.LoginFailed)
+	assert.NoError(t, attemptDB.CreateLoginAttempt(old))
+	assert.NoError(t, attemptDB.CreateLoginAttempt(recent))
+
+	assert.NoError(t, attemptDB.DeleteLoginAttemptsBefore(time.Now().Add(-24*time.Hour)))
+
+	var count int64
+	db.Model(&entity.LoginAttempt{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	_, attemptDB := setupLoginAttemptDB(t)
+	lockout := entity.LoginLockout{Threshold: 5, Duration: time.Hour}
+
+	_, err := attemptDB.FindLoginFailure("nobody@example.com")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	for i := 0; i < 3; i++ {
+		until, err := attemptDB.RecordLoginFailure("nobody@example.com", lockout)
+		assert.NoError(t, err)
+		assert.Nil(t, until)
+	}
+
+	until, err := attemptDB.RecordLoginFailure("nobody@example.com", lockout)
+	assert.NoError(t, err)
+	assert.NotNil(t, until)
+
+	failure, err := attemptDB.FindLoginFailure("nobody@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, failure.Failures)
+	assert.True(t, failure.LoginLockedFor(time.Now()) > 0)
+
+	// Outros emails têm a sua própria contagem
+	until, err = attemptDB.RecordLoginFailure("other@example.com", lockout)
+	assert.NoError(t, err)
+	assert.Nil(t, until)
+}
+
+func TestDeleteLoginFailuresBefore(t *testing.T) {
+	db, attemptDB := setupLoginAttemptDB(t)
+	lockout := entity.LoginLockout{Threshold: 5, Duration: time.Hour}
+	_, err := attemptDB.RecordLoginFailure("old@example.com", lockout)
+	assert.NoError(t, err)
+	_, err = attemptDB.RecordLoginFailure("recent@example.com", lockout)
+	assert.NoError(t, err)
+	db.Model(&entity.LoginFailure{}).Where("1 = 1").UpdateColumn("updated_at", time.Now().Add(-48*time.Hour))
+	// Uma nova falha renova o email
+	_, err = attemptDB.RecordLoginFailure("recent@example.com", lockout)
+	assert.NoError(t, err)
+
+	assert.NoError(t, attemptDB.DeleteLoginFailuresBefore(time.Now().Add(-24*time.Hour)))
+
+	_, err = attemptDB.FindLoginFailure("old@example.com")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = attemptDB.FindLoginFailure("recent@example.com")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+)
+
+type MFARecoveryCodeDB struct {
+	DB *gorm.DB
+}
+
+func NewMFARecoveryCodeDB(db *gorm.DB) *MFARecoveryCodeDB {
+	return &MFARecoveryCodeDB{
+		DB: db,
+	}
+}
+
+// ReplaceMFARecoveryCodes swaps every recovery code of the user, used or
+// not, for the new ones.
+func (mdb *MFARecoveryCodeDB) ReplaceMFARecoveryCodes(userID string, codes []entity.MFARecoveryCode) error {
+	return mdb.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseMFARecoveryCode consumes an unused code of the user with the hash. Only
+// unused codes match, so a code is accepted once even when requests race.
+func (mdb *MFARecoveryCodeDB) UseMFARecoveryCode(userID, hash string) (bool, error) {
+	result := mdb.DB.Model(&entity.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (mdb *MFARecoveryCodeDB) DeleteMFARecoveryCodes(userID string) error {
+	return mdb.DB.Where("user_id = ?", userID).Delete(&entity.MFARecoveryCode{}).Error
+}
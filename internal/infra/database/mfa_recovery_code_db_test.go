@@ -0,0 +1,59 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupMFARecoveryCodeDB(t *testing.T) (*gorm.DB, *MFARecoveryCodeDB) {
+	db := newTestDB(t, &entity.MFARecoveryCode{})
+	return db, NewMFARecoveryCodeDB(db)
+}
+
+func TestMFARecoveryCodeIsSingleUse(t *testing.T) {
+	_, codeDB := setupMFARecoveryCodeDB(t)
+	userID := entityPkg.NewID()
+
+	codes, plain, err := entity.NewMFARecoveryCodes(userID)
+	assert.NoError(t, err)
+	assert.NoError(t, codeDB.ReplaceMFARecoveryCodes(userID.String(), codes))
+
+	used, err := codeDB.UseMFARecoveryCode(userID.String(), entity.HashRecoveryCode(plain[0]))
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = codeDB.UseMFARecoveryCode(userID.String(), entity.HashRecoveryCode(plain[0]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	// Os códigos de um usuário não valem para outro
+	used, err = codeDB.UseMFARecoveryCode(entityPkg.NewID().String(), entity.HashRecoveryCode(plain[1]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
+
+func TestReplaceMFARecoveryCodes(t *testing.T) {
+	db, codeDB := setupMFARecoveryCodeDB(t)
+	userID := entityPkg.NewID()
+
+	old, oldPlain, _ := entity.NewMFARecoveryCodes(userID)
+	assert.NoError(t, codeDB.ReplaceMFARecoveryCodes(userID.String(), old))
+	codes, _, _ := entity.NewMFARecoveryCodes(userID)
+	assert.NoError(t, codeDB.ReplaceMFARecoveryCodes(userID.String(), codes))
+
+	var count int64
+	db.Model(&entity.MFARecoveryCode{}).Where("user_id = ?", userID).Count(&count)
+	assert.Equal(t, int64(entity.RecoveryCodeCount), count)
+
+	used, err := codeDB.UseMFARecoveryCode(userID.String(), entity.HashRecoveryCode(oldPlain[0]))
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	assert.NoError(t, codeDB.DeleteMFARecoveryCodes(userID.String()))
+	db.Model(&entity.MFARecoveryCode{}).Where("user_id = ?", userID).Count(&count)
+	assert.Zero(t, count)
+}
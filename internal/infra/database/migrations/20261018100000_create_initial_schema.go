@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type initialRole struct {
+	ID   string `gorm:"type:char(36);primaryKey"`
+	Name string `gorm:"type:varchar(191);unique;not null"`
+}
+
+func (initialRole) TableName() string { return "roles" }
+
+type initialUser struct {
+	ID       string `gorm:"type:char(36);primaryKey"`
+	Name     string `gorm:"type:varchar(255)"`
+	Email    string `gorm:"type:varchar(255)"`
+	Password string `gorm:"type:varchar(255)"`
+	RoleID   string `gorm:"type:char(36);index"`
+}
+
+func (initialUser) TableName() string { return "users" }
+
+type initialProduct struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	Name      string `gorm:"type:varchar(255)"`
+	Price     float64
+	CreatedAt time.Time
+}
+
+func (initialProduct) TableName() string { return "products" }
+
+type initialRefreshToken struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	UserID    string `gorm:"type:char(36);index"`
+	FamilyID  string `gorm:"type:char(36);index"`
+	TokenHash string `gorm:"type:char(64);uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (initialRefreshToken) TableName() string { return "refresh_tokens" }
+
+type initialRevokedToken struct {
+	JTI       string    `gorm:"type:char(36);primaryKey"`
+	UserID    string    `gorm:"type:char(36);index"`
+	ExpiresAt time.Time `gorm:"index"`
+	RevokedAt time.Time
+}
+
+func (initialRevokedToken) TableName() string { return "revoked_tokens" }
+
+type initialUserTokenRevocation struct {
+	UserID        string `gorm:"type:char(36);primaryKey"`
+	RevokedBefore time.Time
+}
+
+func (initialUserTokenRevocation) TableName() string { return "user_token_revocations" }
+
+// The server used to call AutoMigrate on startup, so existing databases
+// already have these tables. AutoMigrate keeps this first migration safe to
+// run against them: it only creates what is missing.
+func init() {
+	register(Migration{
+		Version: "20261018100000",
+		Name:    "create_initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&initialRole{},
+				&initialUser{},
+				&initialProduct{},
+				&initialRefreshToken{},
+				&initialRevokedToken{},
+				&initialUserTokenRevocation{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&initialUserTokenRevocation{},
+				&initialRevokedToken{},
+				&initialRefreshToken{},
+				&initialProduct{},
+				&initialUser{},
+				&initialRole{},
+			)
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type categoriesCategory struct {
+	ID        string  `gorm:"type:char(36);primaryKey"`
+	Name      string  `gorm:"type:varchar(255);not null"`
+	ParentID  *string `gorm:"type:char(36);index"`
+	CreatedAt time.Time
+}
+
+func (categoriesCategory) TableName() string { return "categories" }
+
+type categoriesProductCategory struct {
+	ProductID  string `gorm:"type:char(36);primaryKey"`
+	CategoryID string `gorm:"type:char(36);primaryKey;index"`
+}
+
+func (categoriesProductCategory) TableName() string { return "product_categories" }
+
+func init() {
+	register(Migration{
+		Version: "20261018110000",
+		Name:    "create_categories",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&categoriesCategory{}, &categoriesProductCategory{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&categoriesProductCategory{}, &categoriesCategory{})
+		},
+	})
+}
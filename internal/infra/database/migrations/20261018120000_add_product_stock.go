@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type stockProduct struct {
+	Stock             int `gorm:"not null;default:0"`
+	LowStockThreshold int `gorm:"not null;default:0"`
+}
+
+func (stockProduct) TableName() string { return "products" }
+
+type stockMovement struct {
+	ID           string    `gorm:"type:char(36);primaryKey"`
+	ProductID    string    `gorm:"type:char(36);index"`
+	Type         string    `gorm:"type:varchar(20);not null"`
+	Quantity     int       `gorm:"not null"`
+	BalanceAfter int       `gorm:"not null"`
+	Reason       string    `gorm:"type:varchar(255)"`
+	ActorID      *string   `gorm:"type:char(36);index"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+func (stockMovement) TableName() string { return "stock_movements" }
+
+func init() {
+	register(Migration{
+		Version: "20261018120000",
+		Name:    "add_product_stock",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"Stock", "LowStockThreshold"} {
+				if err := tx.Migrator().AddColumn(&stockProduct{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&stockMovement{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&stockMovement{}); err != nil {
+				return err
+			}
+			for _, column := range []string{"LowStockThreshold", "Stock"} {
+				if err := tx.Migrator().DropColumn(&stockProduct{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type cartsCart struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	UserID    string `gorm:"type:char(36);uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (cartsCart) TableName() string { return "carts" }
+
+type cartsCartItem struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	CartID    string `gorm:"type:char(36);uniqueIndex:idx_cart_items_cart_product"`
+	ProductID string `gorm:"type:char(36);uniqueIndex:idx_cart_items_cart_product"`
+	Name      string `gorm:"type:varchar(255)"`
+	UnitPrice float64
+	Quantity  int `gorm:"not null"`
+	AddedAt   time.Time
+}
+
+func (cartsCartItem) TableName() string { return "cart_items" }
+
+func init() {
+	register(Migration{
+		Version: "20261018130000",
+		Name:    "create_carts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&cartsCart{}, &cartsCartItem{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&cartsCartItem{}, &cartsCart{})
+		},
+	})
+}
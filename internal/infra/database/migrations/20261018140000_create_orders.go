@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ordersOrder struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	UserID    string `gorm:"type:char(36);index"`
+	Status    string `gorm:"type:varchar(20);not null;index"`
+	Total     float64
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+func (ordersOrder) TableName() string { return "orders" }
+
+type ordersOrderItem struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	OrderID   string `gorm:"type:char(36);index"`
+	ProductID string `gorm:"type:char(36);index"`
+	Name      string `gorm:"type:varchar(255)"`
+	UnitPrice float64
+	Quantity  int `gorm:"not null"`
+	Subtotal  float64
+}
+
+func (ordersOrderItem) TableName() string { return "order_items" }
+
+type ordersOrderTransition struct {
+	ID         string  `gorm:"type:char(36);primaryKey"`
+	OrderID    string  `gorm:"type:char(36);index"`
+	FromStatus string  `gorm:"type:varchar(20)"`
+	ToStatus   string  `gorm:"type:varchar(20);not null"`
+	ActorID    *string `gorm:"type:char(36)"`
+	Note       string  `gorm:"type:varchar(255)"`
+	CreatedAt  time.Time
+}
+
+func (ordersOrderTransition) TableName() string { return "order_transitions" }
+
+func init() {
+	register(Migration{
+		Version: "20261018140000",
+		Name:    "create_orders",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&ordersOrder{}, &ordersOrderItem{}, &ordersOrderTransition{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ordersOrderTransition{}, &ordersOrderItem{}, &ordersOrder{})
+		},
+	})
+}
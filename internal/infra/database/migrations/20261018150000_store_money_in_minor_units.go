@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Os valores existentes estavam em float64 e na moeda padrão (BRL, 2 casas
+// decimais), por isso são convertidos multiplicando por 100.
+const moneyBackfillCurrency = "BRL"
+
+// moneyColumn is a float column being replaced by the amount (in minor units)
+// and currency columns of an embedded Money.
+type moneyColumn struct {
+	table  string
+	legacy string
+	model  interface{}
+}
+
+type moneyProduct struct {
+	Price         float64
+	PriceAmount   int64  `gorm:"not null;default:0"`
+	PriceCurrency string `gorm:"type:char(3)"`
+}
+
+func (moneyProduct) TableName() string { return "products" }
+
+type moneyCartItem struct {
+	UnitPrice         float64
+	UnitPriceAmount   int64  `gorm:"not null;default:0"`
+	UnitPriceCurrency string `gorm:"type:char(3)"`
+}
+
+func (moneyCartItem) TableName() string { return "cart_items" }
+
+type moneyOrder struct {
+	Total         float64
+	TotalAmount   int64  `gorm:"not null;default:0"`
+	TotalCurrency string `gorm:"type:char(3)"`
+}
+
+func (moneyOrder) TableName() string { return "orders" }
+
+type moneyOrderItem struct {
+	UnitPrice         float64
+	UnitPriceAmount   int64  `gorm:"not null;default:0"`
+	UnitPriceCurrency string `gorm:"type:char(3)"`
+	Subtotal          float64
+	SubtotalAmount    int64  `gorm:"not null;default:0"`
+	SubtotalCurrency  string `gorm:"type:char(3)"`
+}
+
+func (moneyOrderItem) TableName() string { return "order_items" }
+
+var moneyColumns = []moneyColumn{
+	{"products", "price", &moneyProduct{}},
+	{"cart_items", "unit_price", &moneyCartItem{}},
+	{"orders", "total", &moneyOrder{}},
+	{"order_items", "unit_price", &moneyOrderItem{}},
+	{"order_items", "subtotal", &moneyOrderItem{}},
+}
+
+func init() {
+	register(Migration{
+		Version: "20261018150000",
+		Name:    "store_money_in_minor_units",
+		Up: func(tx *gorm.DB) error {
+			for _, c := range moneyColumns {
+				for _, column := range []string{c.legacy + "_amount", c.legacy + "_currency"} {
+					if err := tx.Migrator().AddColumn(c.model, column); err != nil {
+						return err
+					}
+				}
+
+				err := tx.Exec("UPDATE "+c.table+" SET "+c.legacy+"_amount = ROUND("+c.legacy+" * 100), "+c.legacy+"_currency = ?",
+					moneyBackfillCurrency).Error
+				if err != nil {
+					return err
+				}
+
+				if err := tx.Migrator().DropColumn(c.model, c.legacy); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(moneyColumns) - 1; i >= 0; i-- {
+				c := moneyColumns[i]
+				if err := tx.Migrator().AddColumn(c.model, c.legacy); err != nil {
+					return err
+				}
+
+				// Volta para float sem considerar a moeda: só havia BRL antes
+				err := tx.Exec("UPDATE " + c.table + " SET " + c.legacy + " = " + c.legacy + "_amount / 100.0").Error
+				if err != nil {
+					return err
+				}
+
+				for _, column := range []string{c.legacy + "_currency", c.legacy + "_amount"} {
+					if err := tx.Migrator().DropColumn(c.model, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
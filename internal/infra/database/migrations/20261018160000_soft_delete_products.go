@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type softDeleteProduct struct {
+	DeletedAt *time.Time `gorm:"index"`
+	DeletedBy *string    `gorm:"type:char(36)"`
+}
+
+func (softDeleteProduct) TableName() string { return "products" }
+
+func init() {
+	register(Migration{
+		Version: "20261018160000",
+		Name:    "soft_delete_products",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"DeletedAt", "DeletedBy"} {
+				if err := tx.Migrator().AddColumn(&softDeleteProduct{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&softDeleteProduct{}, "DeletedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			// No SQLite, remover outra coluna da tabela recria a tabela sem o índice
+			if tx.Migrator().HasIndex(&softDeleteProduct{}, "DeletedAt") {
+				if err := tx.Migrator().DropIndex(&softDeleteProduct{}, "DeletedAt"); err != nil {
+					return err
+				}
+			}
+			for _, column := range []string{"DeletedBy", "DeletedAt"} {
+				if err := tx.Migrator().DropColumn(&softDeleteProduct{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
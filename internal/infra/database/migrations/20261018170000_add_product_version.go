@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type versionProduct struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+func (versionProduct) TableName() string { return "products" }
+
+func init() {
+	register(Migration{
+		Version: "20261018170000",
+		Name:    "add_product_version",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&versionProduct{}, "Version")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&versionProduct{}, "Version")
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type accountFlagsUser struct {
+	Disabled              bool `gorm:"not null;default:false"`
+	PasswordResetRequired bool `gorm:"not null;default:false"`
+}
+
+func (accountFlagsUser) TableName() string { return "users" }
+
+func init() {
+	register(Migration{
+		Version: "20261018180000",
+		Name:    "add_user_account_flags",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&accountFlagsUser{}, "Disabled"); err != nil {
+				return err
+			}
+			return tx.Migrator().AddColumn(&accountFlagsUser{}, "PasswordResetRequired")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&accountFlagsUser{}, "PasswordResetRequired"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&accountFlagsUser{}, "Disabled")
+		},
+	})
+}
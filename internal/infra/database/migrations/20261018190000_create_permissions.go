@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type permissionsPermission struct {
+	ID          string `gorm:"type:char(36);primaryKey"`
+	Name        string `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string `gorm:"type:varchar(255)"`
+}
+
+func (permissionsPermission) TableName() string { return "permissions" }
+
+type permissionsRolePermission struct {
+	RoleID       string `gorm:"type:char(36);primaryKey"`
+	PermissionID string `gorm:"type:char(36);primaryKey;index"`
+}
+
+func (permissionsRolePermission) TableName() string { return "role_permissions" }
+
+// The permissions themselves are created by seed.SeedPermissions, which also
+// grants them to the built-in roles.
+func init() {
+	register(Migration{
+		Version: "20261018190000",
+		Name:    "create_permissions",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&permissionsPermission{}, &permissionsRolePermission{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&permissionsRolePermission{}, &permissionsPermission{})
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordResetToken struct {
+	ID        string    `gorm:"type:char(36);primaryKey"`
+	UserID    string    `gorm:"type:char(36);index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (passwordResetToken) TableName() string { return "password_reset_tokens" }
+
+func init() {
+	register(Migration{
+		Version: "20261018200000",
+		Name:    "create_password_reset_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&passwordResetToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passwordResetToken{})
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type emailVerifiedUser struct {
+	EmailVerifiedAt *time.Time
+}
+
+func (emailVerifiedUser) TableName() string { return "users" }
+
+func init() {
+	register(Migration{
+		Version: "20261018210000",
+		Name:    "add_user_email_verified_at",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&emailVerifiedUser{}, "EmailVerifiedAt"); err != nil {
+				return err
+			}
+			// Contas existentes não ficam bloqueadas quando a verificação for exigida
+			return tx.Model(&emailVerifiedUser{}).Where("email_verified_at IS NULL").
+				Update("email_verified_at", time.Now()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&emailVerifiedUser{}, "EmailVerifiedAt")
+		},
+	})
+}
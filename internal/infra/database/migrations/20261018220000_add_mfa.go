@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type mfaUser struct {
+	MFAEnabled   bool   `gorm:"not null;default:false"`
+	TOTPSecret   string `gorm:"type:varchar(64)"`
+	TOTPLastStep int64  `gorm:"not null;default:0"`
+}
+
+func (mfaUser) TableName() string { return "users" }
+
+type mfaRole struct {
+	RequireMFA bool `gorm:"not null;default:false"`
+}
+
+func (mfaRole) TableName() string { return "roles" }
+
+type mfaRecoveryCode struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	UserID    string `gorm:"type:char(36);index"`
+	CodeHash  string `gorm:"type:char(64);index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (mfaRecoveryCode) TableName() string { return "mfa_recovery_codes" }
+
+func init() {
+	register(Migration{
+		Version: "20261018220000",
+		Name:    "add_mfa",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"MFAEnabled", "TOTPSecret", "TOTPLastStep"} {
+				if err := tx.Migrator().AddColumn(&mfaUser{}, column); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().AddColumn(&mfaRole{}, "RequireMFA"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&mfaRecoveryCode{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&mfaRecoveryCode{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&mfaRole{}, "RequireMFA"); err != nil {
+				return err
+			}
+			for _, column := range []string{"TOTPLastStep", "TOTPSecret", "MFAEnabled"} {
+				if err := tx.Migrator().DropColumn(&mfaUser{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
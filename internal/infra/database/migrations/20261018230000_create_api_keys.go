@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKey struct {
+	ID         string `gorm:"type:char(36);primaryKey"`
+	UserID     string `gorm:"type:char(36);index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(16);not null"`
+	KeyHash    string `gorm:"type:char(64);uniqueIndex"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKey) TableName() string { return "api_keys" }
+
+type apiKeyScope struct {
+	APIKeyID     string `gorm:"type:char(36);primaryKey"`
+	PermissionID string `gorm:"type:char(36);primaryKey;index"`
+}
+
+func (apiKeyScope) TableName() string { return "api_key_scopes" }
+
+func init() {
+	register(Migration{
+		Version: "20261018230000",
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&apiKey{}, &apiKeyScope{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiKeyScope{}, &apiKey{})
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginProtectionUser struct {
+	FailedLogins int `gorm:"not null;default:0"`
+	LockedUntil  *time.Time
+}
+
+func (loginProtectionUser) TableName() string { return "users" }
+
+type loginAttempt struct {
+	ID        string    `gorm:"type:char(36);primaryKey"`
+	UserID    *string   `gorm:"type:char(36);index"`
+	Email     string    `gorm:"type:varchar(255)"`
+	IP        string    `gorm:"type:varchar(45)"`
+	UserAgent string    `gorm:"type:varchar(255)"`
+	Outcome   string    `gorm:"type:varchar(20)"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (loginAttempt) TableName() string { return "login_attempts" }
+
+type loginFailure struct {
+	Email       string `gorm:"type:varchar(255);primaryKey"`
+	Failures    int    `gorm:"not null;default:0"`
+	LockedUntil *time.Time
+	UpdatedAt   time.Time `gorm:"index"`
+}
+
+func (loginFailure) TableName() string { return "login_failures" }
+
+func init() {
+	register(Migration{
+		Version: "20261019000000",
+		Name:    "add_login_protection",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"FailedLogins", "LockedUntil"} {
+				if err := tx.Migrator().AddColumn(&loginProtectionUser{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&loginAttempt{}, &loginFailure{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&loginFailure{}, &loginAttempt{}); err != nil {
+				return err
+			}
+			for _, column := range []string{"LockedUntil", "FailedLogins"} {
+				if err := tx.Migrator().DropColumn(&loginProtectionUser{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
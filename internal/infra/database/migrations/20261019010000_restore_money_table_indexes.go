@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// No SQLite, store_money_in_minor_units remove colunas recriando as tabelas,
+// o que perdeu os índices criados junto com elas.
+
+type indexedCartItem struct {
+	CartID    string `gorm:"type:char(36);uniqueIndex:idx_cart_items_cart_product"`
+	ProductID string `gorm:"type:char(36);uniqueIndex:idx_cart_items_cart_product"`
+}
+
+func (indexedCartItem) TableName() string { return "cart_items" }
+
+type indexedOrder struct {
+	UserID    string `gorm:"type:char(36);index"`
+	Status    string `gorm:"type:varchar(20);index"`
+	CreatedAt string `gorm:"index"`
+}
+
+func (indexedOrder) TableName() string { return "orders" }
+
+type indexedOrderItem struct {
+	OrderID   string `gorm:"type:char(36);index"`
+	ProductID string `gorm:"type:char(36);index"`
+}
+
+func (indexedOrderItem) TableName() string { return "order_items" }
+
+// tableIndex is an index identified by a field name or, for composite
+// indexes, by the index name.
+type tableIndex struct {
+	model interface{}
+	name  string
+}
+
+var moneyTableIndexes = []tableIndex{
+	{&indexedCartItem{}, "idx_cart_items_cart_product"},
+	{&indexedOrder{}, "UserID"},
+	{&indexedOrder{}, "Status"},
+	{&indexedOrder{}, "CreatedAt"},
+	{&indexedOrderItem{}, "OrderID"},
+	{&indexedOrderItem{}, "ProductID"},
+}
+
+func init() {
+	register(Migration{
+		Version: "20261019010000",
+		Name:    "restore_money_table_indexes",
+		Up: func(tx *gorm.DB) error {
+			for _, i := range moneyTableIndexes {
+				if tx.Migrator().HasIndex(i.model, i.name) {
+					continue
+				}
+				if err := tx.Migrator().CreateIndex(i.model, i.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, i := range moneyTableIndexes {
+				if !tx.Migrator().HasIndex(i.model, i.name) {
+					continue
+				}
+				if err := tx.Migrator().DropIndex(i.model, i.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
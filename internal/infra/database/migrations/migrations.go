@@ -1,15 +1,193 @@
 package migrations
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
-	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"gorm.io/gorm"
 )
 
-func Migrate(db *gorm.DB) {
-	err := db.AutoMigrate(&entity.Role{}, &entity.User{})
+// Migration is a single reversible schema change. Migrations are registered
+// from init functions, one file per migration, and applied in Version order.
+//
+// Up and Down must not use the structs from internal/entity: those keep
+// evolving, while a migration has to describe the schema as it was when it
+// was written. Declare a local snapshot of the tables instead.
+type Migration struct {
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row of the table that tracks applied migrations.
+type SchemaMigration struct {
+	Version   string    `gorm:"type:varchar(14);primaryKey"`
+	Name      string    `gorm:"type:varchar(255)"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// MigrationStatus describes a registered migration and whether it was applied.
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+}
+
+var registry = map[string]Migration{}
+
+func register(m Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic(fmt.Sprintf("migration %s registered twice", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All returns the registered migrations sorted by version.
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{
+		DB:         db,
+		Migrations: All(),
+	}
+}
+
+// Migrate applies every pending migration.
+func Migrate(db *gorm.DB) error {
+	_, err := NewMigrator(db).Up()
+	return err
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.DB.AutoMigrate(&SchemaMigration{})
+}
+
+func (m *Migrator) applied() (map[string]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := m.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// Pending returns the migrations that were not applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration, each one in its own transaction.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last n applied migrations, newest first.
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, errors.New("number of migrations to revert must be at least 1")
+	}
+
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := m.DB.Order("version desc").Limit(n).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, row := range rows {
+		migration, ok := registry[row.Version]
+		if !ok {
+			return done, fmt.Errorf("migration %s is applied but not registered", row.Version)
+		}
+
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, "version = ?", row.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lists every registered migration with the time it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
 	if err != nil {
-		log.Fatalf("Erro ao rodar migração: %v", err)
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		s := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
 	}
+	return status, nil
 }
@@ -0,0 +1,172 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func setupMigrator(t *testing.T) *Migrator {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("could not open db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	// Cada conexão teria o seu próprio banco em memória
+	sqlDB.SetMaxOpenConns(1)
+	return NewMigrator(db)
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	migrator := setupMigrator(t)
+	total := len(migrator.Migrations)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, total)
+
+	done, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, done, total)
+
+	pending, err = migrator.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	done, err = migrator.Down(total)
+	assert.NoError(t, err)
+	assert.Len(t, done, total)
+
+	pending, err = migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, total)
+	assert.False(t, migrator.DB.Migrator().HasTable("users"))
+
+	// Aplicar de novo depois de reverter deve funcionar
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+}
+
+// As versões são instantes no formato de Create, senão a ordem seria acidental
+func TestVersionsAreTimestamps(t *testing.T) {
+	for _, m := range All() {
+		_, err := time.Parse("20060102150405", m.Version)
+		assert.NoError(t, err, m.Version)
+	}
+}
+
+func TestMigrateDownRequiresPositiveCount(t *testing.T) {
+	migrator := setupMigrator(t)
+	_, err := migrator.Down(0)
+	assert.Error(t, err)
+}
+
+// Garante que o schema criado pelas migrações tem todas as colunas e índices
+// que as entidades esperam.
+func TestMigrationsMatchEntities(t *testing.T) {
+	migrator := setupMigrator(t)
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	models := []interface{}{
+		&entity.Role{},
+		&entity.Permission{},
+		&entity.User{},
+		&entity.Product{},
+		&entity.RefreshToken{},
+		&entity.PasswordResetToken{},
+		&entity.MFARecoveryCode{},
+		&entity.APIKey{},
+		&entity.LoginAttempt{},
+		&entity.LoginFailure{},
+		&entity.RevokedToken{},
+		&entity.UserTokenRevocation{},
+		&entity.Category{},
+		&entity.StockMovement{},
+		&entity.Cart{},
+		&entity.CartItem{},
+		&entity.Order{},
+		&entity.OrderItem{},
+		&entity.OrderTransition{},
+	}
+
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, migrator.DB.NamingStrategy)
+		assert.NoError(t, err)
+		assert.True(t, migrator.DB.Migrator().HasTable(s.Table), "missing table %s", s.Table)
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, migrator.DB.Migrator().HasColumn(s.Table, field.DBName), "missing column %s.%s", s.Table, field.DBName)
+		}
+		for name := range s.ParseIndexes() {
+			assert.True(t, migrator.DB.Migrator().HasIndex(s.Table, name), "missing index %s.%s", s.Table, name)
+		}
+	}
+
+	for _, table := range []string{"product_categories", "role_permissions", "api_key_scopes"} {
+		assert.True(t, migrator.DB.Migrator().HasTable(table), "missing join table %s", table)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+
+	path, err := Create(dir, "Add Product Stock!", now)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "20261018123000_add_product_stock.go"), path)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(content), `Version: "20261018123000"`))
+
+	_, err = Create(dir, "add product stock", now)
+	assert.Error(t, err)
+
+	_, err = Create(dir, "  ", now)
+	assert.Error(t, err)
+}
+
+func TestMoneyMigrationBackfillsAmounts(t *testing.T) {
+	migrator := setupMigrator(t)
+	_, err := migrator.Up()
+	assert.NoError(t, err)
+
+	// Volta para antes da migração de valores e insere um preço em float
+	after := 0
+	for _, m := range migrator.Migrations {
+		if m.Version >= "20261018150000" {
+			after++
+		}
+	}
+	_, err = migrator.Down(after)
+	assert.NoError(t, err)
+	err = migrator.DB.Exec("INSERT INTO products (id, name, price, created_at) VALUES (?, ?, ?, ?)",
+		"7b4d3c5e-7c0e-4f5c-9d6a-1f1c2f0b8a11", "Blusa", 19.99, time.Now()).Error
+	assert.NoError(t, err)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	var product entity.Product
+	assert.NoError(t, migrator.DB.First(&product).Error)
+	assert.Equal(t, int64(1999), product.Price.Amount)
+	assert.Equal(t, "BRL", product.Price.Currency)
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type OrderDB struct {
+	DB *gorm.DB
+}
+
+func NewOrderDB(db *gorm.DB) *OrderDB {
+	return &OrderDB{
+		DB: db,
+	}
+}
+
+// CreateOrder persists the order and takes its items out of the stock in a
+// single transaction, so an order is never placed without the goods. The
+// cart items the order came from, if any, are removed from the cart in the
+// same transaction. Only those are removed, so items added meanwhile stay,
+// and ErrCartChanged is returned when any of them was changed or removed.
+func (odb *OrderDB) CreateOrder(order *entity.Order, cartItems []entity.CartItem) error {
+	return odb.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range order.Items {
+			movement, err := entity.NewStockMovement(item.ProductID, entity.StockMovementSale, item.Quantity,
+				fmt.Sprintf("Order %s", order.ID), &order.UserID)
+			if err != nil {
+				return err
+			}
+			if err := adjustStock(tx, movement); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+
+		if len(cartItems) == 0 {
+			return nil
+		}
+		for _, item := range cartItems {
+			result := tx.Where("id = ? AND quantity = ?", item.ID, item.Quantity).Delete(&entity.CartItem{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return entity.ErrCartChanged
+			}
+		}
+		return touchCart(tx, cartItems[0].CartID.String())
+	})
+}
+
+func (odb *OrderDB) FindOrderByID(id string) (*entity.Order, error) {
+	return findOrder(odb.DB, id)
+}
+
+func findOrder(db *gorm.DB, id string) (*entity.Order, error) {
+	var order entity.Order
+	err := db.
+		Preload("Items").
+		Preload("Transitions", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+		First(&order, "id = ?", id).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindOrders returns the newest orders first, without their history. With a
+// cursor in the filter the page number is ignored.
+func (odb *OrderDB) FindOrders(page, limit int, filter OrderFilter) ([]entity.Order, error) {
+	query := odb.DB.Preload("Items")
+
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if filter.After != nil {
+		query = afterCursor(query, filter.After)
+	} else {
+		query = query.Order("created_at desc").Order("id desc").Offset((page - 1) * limit)
+	}
+
+	var orders []entity.Order
+	err := query.Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+// TransitionOrder moves the order to the given status and records the
+// transition. The status is changed with a conditional UPDATE, so two
+// concurrent transitions from the same status can't both succeed. Cancelled
+// and refunded-before-shipping orders return their items to the stock.
+func (odb *OrderDB) TransitionOrder(id string, to entity.OrderStatus, actorID *entityPkg.ID, note string) (*entity.Order, error) {
+	var order *entity.Order
+
+	err := odb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = findOrder(tx, id)
+		if err != nil {
+			return err
+		}
+
+		transition, err := order.Transition(to, actorID, note)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.Order{}).
+			Where("id = ? AND status = ?", id, transition.From).
+			Updates(map[string]interface{}{"status": transition.To, "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrOrderStatusConflict
+		}
+
+		if transition.Restocks() {
+			for _, item := range order.Items {
+				movement, err := entity.NewStockMovement(item.ProductID, entity.StockMovementReturn, item.Quantity,
+					fmt.Sprintf("Order %s %s", order.ID, transition.To), actorID)
+				if err != nil {
+					return err
+				}
+				// Produtos na lixeira também recebem o estoque de volta, mas os
+				// já expurgados são ignorados
+				err = adjustStock(tx.Unscoped().Session(&gorm.Session{}), movement)
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+			}
+		}
+
+		return tx.Create(transition).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupOrderDB(t *testing.T) (*gorm.DB, *OrderDB, *entity.Product) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{}, &entity.StockMovement{}, &entity.Cart{}, &entity.CartItem{}, &entity.Order{}, &entity.OrderItem{}, &entity.OrderTransition{})
+	product, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	product.Stock = 5
+	db.Create(product)
+	return db, NewOrderDB(db), product
+}
+
+func newTestOrder(t *testing.T, product *entity.Product, quantity int) *entity.Order {
+	item, err := entity.NewOrderItem(product, quantity)
+	assert.NoError(t, err)
+	order, err := entity.NewOrder(entityPkg.NewID(), []entity.OrderItem{*item})
+	assert.NoError(t, err)
+	return order
+}
+
+func stockOf(db *gorm.DB, product *entity.Product) int {
+	var found entity.Product
+	db.First(&found, "id = ?", product.ID)
+	return found.Stock
+}
+
+func TestCreateOrder(t *testing.T) {
+	db, orderDB, product := setupOrderDB(t)
+	cartDB := NewCartDB(db)
+
+	order := newTestOrder(t, product, 2)
+	cart, _ := cartDB.FindOrCreateCart(order.UserID.String())
+	cartItem, _ := entity.NewCartItem(cart.ID, product, 2)
+	cartDB.AddCartItem(cartItem)
+
+	assert.NoError(t, orderDB.CreateOrder(order, []entity.CartItem{*cartItem}))
+	assert.Equal(t, 3, stockOf(db, product))
+
+	found, err := orderDB.FindOrderByID(order.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderPending, found.Status)
+	assert.Len(t, found.Items, 1)
+	assert.Len(t, found.Transitions, 1)
+
+	cart, _ = cartDB.FindOrCreateCart(order.UserID.String())
+	assert.Empty(t, cart.Items)
+
+	var movements []entity.StockMovement
+	db.Where("product_id = ? AND type = ?", product.ID, entity.StockMovementSale).Find(&movements)
+	assert.Len(t, movements, 1)
+}
+
+func TestCreateOrderKeepsItemsAddedMeanwhile(t *testing.T) {
+	db, orderDB, product := setupOrderDB(t)
+	cartDB := NewCartDB(db)
+	other, _ := entity.NewProduct("Saia", entityPkg.MustParseMoney("5.00", "BRL"))
+	db.Create(other)
+
+	order := newTestOrder(t, product, 2)
+	cart, _ := cartDB.FindOrCreateCart(order.UserID.String())
+	ordered, _ := entity.NewCartItem(cart.ID, product, 2)
+	cartDB.AddCartItem(ordered)
+	// Adicionado depois de o pedido ter lido o carrinho
+	added, _ := entity.NewCartItem(cart.ID, other, 1)
+	cartDB.AddCartItem(added)
+
+	assert.NoError(t, orderDB.CreateOrder(order, []entity.CartItem{*ordered}))
+
+	cart, _ = cartDB.FindOrCreateCart(order.UserID.String())
+	assert.Len(t, cart.Items, 1)
+	assert.Equal(t, other.ID, cart.Items[0].ProductID)
+}
+
+func TestCreateOrderWithChangedCart(t *testing.T) {
+	db, orderDB, product := setupOrderDB(t)
+	cartDB := NewCartDB(db)
+
+	order := newTestOrder(t, product, 2)
+	cart, _ := cartDB.FindOrCreateCart(order.UserID.String())
+	item, _ := entity.NewCartItem(cart.ID, product, 2)
+	cartDB.AddCartItem(item)
+	assert.NoError(t, cartDB.UpdateCartItemQuantity(cart.ID.String(), product.ID.String(), 3))
+
+	assert.ErrorIs(t, orderDB.CreateOrder(order, []entity.CartItem{*item}), entity.ErrCartChanged)
+	assert.Equal(t, 5, stockOf(db, product))
+	_, err := orderDB.FindOrderByID(order.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCreateOrderWithInsufficientStock(t *testing.T) {
+	db, orderDB, product := setupOrderDB(t)
+
+	order := newTestOrder(t, product, 6)
+	assert.Equal(t, entity.ErrInsufficientStock, orderDB.CreateOrder(order, nil))
+	assert.Equal(t, 5, stockOf(db, product))
+
+	_, err := orderDB.FindOrderByID(order.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestFindOrders(t *testing.T) {
+	_, orderDB, product := setupOrderDB(t)
+
+	first := newTestOrder(t, product, 1)
+	second := newTestOrder(t, product, 1)
+	assert.NoError(t, orderDB.CreateOrder(first, nil))
+	assert.NoError(t, orderDB.CreateOrder(second, nil))
+	_, err := orderDB.TransitionOrder(second.ID.String(), entity.OrderPaid, nil, "")
+	assert.NoError(t, err)
+
+	orders, err := orderDB.FindOrders(1, 10, OrderFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, orders, 2)
+
+	orders, err = orderDB.FindOrders(1, 10, OrderFilter{UserID: first.UserID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, first.ID, orders[0].ID)
+
+	orders, err = orderDB.FindOrders(1, 10, OrderFilter{Status: string(entity.OrderPaid)})
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, second.ID, orders[0].ID)
+}
+
+func TestTransitionOrder(t *testing.T) {
+	db, orderDB, product := setupOrderDB(t)
+	actorID := entityPkg.NewID()
+
+	order := newTestOrder(t, product, 2)
+	assert.NoError(t, orderDB.CreateOrder(order, nil))
+
+	_, err := orderDB.TransitionOrder(order.ID.String(), entity.OrderDelivered, &actorID, "")
+	assert.Equal(t, entity.ErrInvalidTransition, err)
+
+	updated, err := orderDB.TransitionOrder(order.ID.String(), entity.OrderPaid, &actorID, "pix")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderPaid, updated.Status)
+	assert.Equal(t, 3, stockOf(db, product))
+
+	// O cancelamento devolve os itens ao estoque
+	_, err = orderDB.TransitionOrder(order.ID.String(), entity.OrderCancelled, &actorID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, stockOf(db, product))
+
+	found, _ := orderDB.FindOrderByID(order.ID.String())
+	assert.Equal(t, entity.OrderCancelled, found.Status)
+	assert.Len(t, found.Transitions, 3)
+	assert.Equal(t, entity.OrderPaid, found.Transitions[2].From)
+	assert.Equal(t, actorID, *found.Transitions[2].ActorID)
+
+	_, err = orderDB.TransitionOrder(entityPkg.NewID().String(), entity.OrderPaid, &actorID, "")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestTransitionOrderRestocksTrashedProducts(t *testing.T) {
+	db, orderDB, product := setupOrderDB(t)
+	productDB := NewProductDB(db)
+
+	order := newTestOrder(t, product, 2)
+	assert.NoError(t, orderDB.CreateOrder(order, nil))
+	// O pedido baixou o estoque, o que muda a versão do produto
+	current, err := productDB.FindProductByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.NoError(t, productDB.DeleteProduct(current, nil))
+
+	// O produto na lixeira recebe o estoque de volta para quando for restaurado
+	_, err = orderDB.TransitionOrder(order.ID.String(), entity.OrderCancelled, nil, "")
+	assert.NoError(t, err)
+
+	restored, err := productDB.RestoreProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, restored.Stock)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenDB struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetTokenDB(db *gorm.DB) *PasswordResetTokenDB {
+	return &PasswordResetTokenDB{
+		DB: db,
+	}
+}
+
+func (pdb *PasswordResetTokenDB) CreatePasswordResetToken(token *entity.PasswordResetToken) error {
+	return pdb.DB.Create(token).Error
+}
+
+func (pdb *PasswordResetTokenDB) FindPasswordResetTokenByHash(hash string) (*entity.PasswordResetToken, error) {
+	var token entity.PasswordResetToken
+	err := pdb.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkPasswordResetTokenUsed flags the token as consumed. Only unused tokens
+// match, so when two requests race with the same token only one of them gets
+// true back.
+func (pdb *PasswordResetTokenDB) MarkPasswordResetTokenUsed(id string) (bool, error) {
+	result := pdb.DB.Model(&entity.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateUserPasswordResetTokens marks every pending token of the user as
+// used, so older emails stop working once the password is changed.
+func (pdb *PasswordResetTokenDB) InvalidateUserPasswordResetTokens(userID string) error {
+	return pdb.DB.Model(&entity.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).
+		Error
+}
+
+func (pdb *PasswordResetTokenDB) DeleteExpiredPasswordResetTokens() error {
+	return pdb.DB.Where("expires_at < ?", time.Now()).Delete(&entity.PasswordResetToken{}).Error
+}
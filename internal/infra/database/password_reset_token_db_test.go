@@ -0,0 +1,79 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupPasswordResetTokenDB(t *testing.T) (*gorm.DB, *PasswordResetTokenDB) {
+	db := newTestDB(t, &entity.PasswordResetToken{})
+	return db, NewPasswordResetTokenDB(db)
+}
+
+func TestPasswordResetTokenIsSingleUse(t *testing.T) {
+	_, tokenDB := setupPasswordResetTokenDB(t)
+
+	token, plain, err := entity.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.CreatePasswordResetToken(token))
+
+	found, err := tokenDB.FindPasswordResetTokenByHash(entityPkg.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, found.ID)
+
+	marked, err := tokenDB.MarkPasswordResetTokenUsed(token.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, marked)
+
+	marked, err = tokenDB.MarkPasswordResetTokenUsed(token.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, marked)
+
+	found, err = tokenDB.FindPasswordResetTokenByHash(token.TokenHash)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, found.Validate(), entity.ErrPasswordResetTokenUsed)
+}
+
+func TestInvalidateUserPasswordResetTokens(t *testing.T) {
+	_, tokenDB := setupPasswordResetTokenDB(t)
+	userID := entityPkg.NewID()
+
+	first, _, _ := entity.NewPasswordResetToken(userID, time.Hour)
+	second, _, _ := entity.NewPasswordResetToken(userID, time.Hour)
+	other, _, _ := entity.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+	for _, token := range []*entity.PasswordResetToken{first, second, other} {
+		assert.NoError(t, tokenDB.CreatePasswordResetToken(token))
+	}
+
+	assert.NoError(t, tokenDB.InvalidateUserPasswordResetTokens(userID.String()))
+
+	for _, token := range []*entity.PasswordResetToken{first, second} {
+		found, err := tokenDB.FindPasswordResetTokenByHash(token.TokenHash)
+		assert.NoError(t, err)
+		assert.NotNil(t, found.UsedAt)
+	}
+
+	found, err := tokenDB.FindPasswordResetTokenByHash(other.TokenHash)
+	assert.NoError(t, err)
+	assert.Nil(t, found.UsedAt)
+}
+
+func TestDeleteExpiredPasswordResetTokens(t *testing.T) {
+	db, tokenDB := setupPasswordResetTokenDB(t)
+
+	expired, _, _ := entity.NewPasswordResetToken(entityPkg.NewID(), -time.Minute)
+	valid, _, _ := entity.NewPasswordResetToken(entityPkg.NewID(), time.Hour)
+	assert.NoError(t, tokenDB.CreatePasswordResetToken(expired))
+	assert.NoError(t, tokenDB.CreatePasswordResetToken(valid))
+
+	assert.NoError(t, tokenDB.DeleteExpiredPasswordResetTokens())
+
+	var count int64
+	db.Model(&entity.PasswordResetToken{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PermissionDB struct {
+	DB *gorm.DB
+}
+
+func NewPermissionDB(db *gorm.DB) *PermissionDB {
+	return &PermissionDB{
+		DB: db,
+	}
+}
+
+func (pdb *PermissionDB) CreatePermission(permission *entity.Permission) error {
+	return pdb.DB.Create(permission).Error
+}
+
+func (pdb *PermissionDB) FindAllPermissions() ([]entity.Permission, error) {
+	var permissions []entity.Permission
+	err := pdb.DB.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+func (pdb *PermissionDB) FindPermissionsByNames(names []string) ([]entity.Permission, error) {
+	var permissions []entity.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	err := pdb.DB.Where("name IN ?", names).Order("name").Find(&permissions).Error
+	return permissions, err
+}
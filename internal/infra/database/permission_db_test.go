@@ -0,0 +1,30 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAllPermissions(t *testing.T) {
+	_, _, permissionDB := setupRoleDB(t)
+
+	for _, name := range []string{"user:write", "order:read"} {
+		permission, _ := entity.NewPermission(name, "")
+		assert.NoError(t, permissionDB.CreatePermission(permission))
+	}
+
+	duplicate, _ := entity.NewPermission("order:read", "")
+	assert.Error(t, permissionDB.CreatePermission(duplicate))
+
+	permissions, err := permissionDB.FindAllPermissions()
+	assert.NoError(t, err)
+	assert.Len(t, permissions, 2)
+	assert.Equal(t, "order:read", permissions[0].Name)
+	assert.Equal(t, "user:write", permissions[1].Name)
+
+	permissions, err = permissionDB.FindPermissionsByNames(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, permissions)
+}
@@ -1,8 +1,14 @@
 package database
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductDB struct {
@@ -21,33 +27,242 @@ func (pdb *ProductDB) CreateProduct(product *entity.Product) error {
 
 func (pdb *ProductDB) FindProductByID(id string) (*entity.Product, error) {
 	var product entity.Product
-	err := pdb.DB.First(&product, "id=?", id).Error
+	err := pdb.DB.Preload("Categories").First(&product, "id=?", id).Error
 	return &product, err
 }
 
-func (pdb *ProductDB) UpdateProduct(product *entity.Product) error {
-	return pdb.DB.Save(product).Error
+func (pdb *ProductDB) FindProductsByIDs(ids []string) ([]entity.Product, error) {
+	var products []entity.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := pdb.DB.Where("id IN ?", ids).Find(&products).Error
+	return products, err
 }
 
-func (pdb *ProductDB) DeleteProduct(id string) error {
-	result := pdb.DB.Delete(&entity.Product{}, "id = ?", id)
-	if result.RowsAffected == 0 {
+// UpdateProduct saves the product if it is still at the version it was read
+// with, and moves it to the next version. A product changed in the meantime
+// gives entity.ErrProductVersionConflict. Non-nil categories replace the
+// ones linked to the product in the same transaction, while nil keeps them.
+func (pdb *ProductDB) UpdateProduct(product *entity.Product, categories []entity.Category) error {
+	err := pdb.DB.Transaction(func(tx *gorm.DB) error {
+		// O estoque só muda pelo livro de movimentações (StockDB.AdjustStock)
+		result := tx.Model(&entity.Product{}).
+			Where("id = ? AND version = ?", product.ID.String(), product.Version).
+			Updates(map[string]interface{}{
+				"name":                product.Name,
+				"price_amount":        product.Price.Amount,
+				"price_currency":      product.Price.Currency,
+				"low_stock_threshold": product.LowStockThreshold,
+				"version":             gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return versionConflict(tx, product.ID.String())
+		}
+
+		if categories == nil {
+			return nil
+		}
+		return tx.Model(product).Association("Categories").Replace(categories)
+	})
+	if err != nil {
+		return err
+	}
+
+	product.Version++
+	if categories != nil {
+		product.Categories = categories
+	}
+	return nil
+}
+
+// versionConflict tells why a write conditioned on the version of the
+// product changed nothing: it is gone, or it is at another version.
+func versionConflict(db *gorm.DB, id string) error {
+	var count int64
+	if err := db.Model(&entity.Product{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
 		return gorm.ErrRecordNotFound
 	}
-	return result.Error
+	return entity.ErrProductVersionConflict
 }
 
-func (pdb *ProductDB) FindAllProducts(page, limit int, sort string) ([]entity.Product, error) {
-	var products []entity.Product
-	offset := (page - 1) * limit // Calculando o offset corretamente
+// DeleteProduct moves the product to the trash if it is still at the
+// version it was read with, like UpdateProduct. It keeps its categories, so
+// a restored product comes back as it was.
+func (pdb *ProductDB) DeleteProduct(product *entity.Product, actorID *entityPkg.ID) error {
+	result := pdb.DB.Model(&entity.Product{}).
+		Where("id = ? AND version = ?", product.ID.String(), product.Version).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "deleted_by": actorID, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionConflict(pdb.DB, product.ID.String())
+	}
 
-	// Executando a query com paginação e ordenação seguras
-	err := pdb.DB.
-		Order("created_at " + sort).
+	product.Version++
+	return nil
+}
+
+// FindDeletedProducts returns a page of the trash, the latest removed first,
+// and the number of products in it.
+func (pdb *ProductDB) FindDeletedProducts(page, limit int) ([]entity.Product, int64, error) {
+	trash := pdb.DB.Unscoped().Model(&entity.Product{}).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := trash.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var products []entity.Product
+	err := pdb.DB.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Preload("Categories").
+		Order("deleted_at desc").
+		Order("id").
 		Limit(limit).
-		Offset(offset).
+		Offset((page - 1) * limit).
 		Find(&products).
 		Error
+	return products, total, err
+}
 
-	return products, err
+// RestoreProduct takes the product out of the trash.
+func (pdb *ProductDB) RestoreProduct(id string) (*entity.Product, error) {
+	result := pdb.DB.Unscoped().Model(&entity.Product{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "deleted_by": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return pdb.FindProductByID(id)
+}
+
+// PurgeProduct deletes a product of the trash for good. Products that were
+// not removed first are not found.
+func (pdb *ProductDB) PurgeProduct(id string) error {
+	purged, err := pdb.purge("id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedProducts deletes for good the products removed before the
+// given time and returns how many were purged.
+func (pdb *ProductDB) PurgeDeletedProducts(before time.Time) (int64, error) {
+	return pdb.purge("deleted_at IS NOT NULL AND deleted_at < ?", before)
+}
+
+// purge hard deletes the products matching the condition, with their links
+// to categories, ignoring the trash scope.
+func (pdb *ProductDB) purge(condition string, args ...interface{}) (int64, error) {
+	var purged int64
+	err := pdb.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Unscoped().Model(&entity.Product{}).Where(condition, args...).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Exec("DELETE FROM product_categories WHERE product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&entity.Product{}, "id IN ?", ids)
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// FindAllProducts returns the page of products described by the query and
+// the number of products matching its filters.
+func (pdb *ProductDB) FindAllProducts(query ProductQuery) ([]entity.Product, int64, error) {
+	var total int64
+	if err := pdb.filterProducts(query).Model(&entity.Product{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	find := pdb.filterProducts(query).Preload("Categories").Limit(query.Limit)
+
+	if query.After != nil {
+		find = afterCursor(find, query.After)
+	} else {
+		desc := false
+		for _, sort := range query.Sort {
+			column, ok := ProductSortColumns[sort.Field]
+			if !ok {
+				return nil, 0, fmt.Errorf("invalid sort field %q", sort.Field)
+			}
+			find = find.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: sort.Desc})
+			desc = sort.Desc
+		}
+		find = find.
+			Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc}).
+			Offset((query.Page - 1) * query.Limit) // Calculando o offset corretamente
+	}
+
+	var products []entity.Product
+	err := find.Find(&products).Error
+
+	return products, total, err
+}
+
+func (pdb *ProductDB) filterProducts(query ProductQuery) *gorm.DB {
+	db := pdb.DB
+
+	if len(query.CategoryIDs) > 0 {
+		db = db.Where("id IN (?)", pdb.DB.Table("product_categories").
+			Select("product_id").
+			Where("category_id IN ?", query.CategoryIDs))
+	}
+
+	if query.InStock != nil {
+		if *query.InStock {
+			db = db.Where("stock > 0")
+		} else {
+			db = db.Where("stock <= 0")
+		}
+	}
+
+	if query.PriceMin != nil {
+		db = db.Where("price_currency = ? AND price_amount >= ?", query.PriceMin.Currency, query.PriceMin.Amount)
+	}
+
+	if query.PriceMax != nil {
+		db = db.Where("price_currency = ? AND price_amount <= ?", query.PriceMax.Currency, query.PriceMax.Amount)
+	}
+
+	if query.NameContains != "" {
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(query.NameContains))+"%")
+	}
+
+	if query.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *query.CreatedAfter)
+	}
+
+	if query.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *query.CreatedBefore)
+	}
+
+	return db
+}
+
+// escapeLike escapes the LIKE wildcards so user input matches literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
 }
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
 	"github.com/stretchr/testify/assert"
 	_ "github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -19,9 +22,9 @@ func TestCreateProduct(t *testing.T) {
 		t.Error(err)
 	}
 
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.Category{})
 
-	product, err := entity.NewProduct("Blusa", 9.99)
+	product, err := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
 
 	assert.NoError(t, err)
 
@@ -49,24 +52,25 @@ func TestFindAllProducts(t *testing.T) {
 		t.Error(err)
 	}
 
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.Category{})
 
 	for i := 1; i < 24; i++ {
-		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100)
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.NewMoney(rand.Int63n(10000)+1, "BRL"))
 		assert.NoError(t, err)
 		db.Create(product)
 	}
 
 	productDB := NewProductDB(db)
 
-	products, err := productDB.FindAllProducts(1, 10, "asc")
+	products, total, err := productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, Sort: []ProductSort{{Field: "created_at"}}})
 
 	assert.NoError(t, err)
+	assert.Equal(t, int64(23), total)
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 1", products[0].Name)
 	assert.Equal(t, "Product 10", products[9].Name)
 
-	products, err = productDB.FindAllProducts(2, 10, "asc")
+	products, _, err = productDB.FindAllProducts(ProductQuery{Page: 2, Limit: 10, Sort: []ProductSort{{Field: "created_at"}}})
 
 	assert.NoError(t, err)
 	assert.Len(t, products, 10)
@@ -81,9 +85,9 @@ func TestFindProductById(t *testing.T) {
 		t.Error(err)
 	}
 
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.Category{})
 
-	product, err := entity.NewProduct("Blusa", 9.99)
+	product, err := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
 
 	assert.NoError(t, err)
 
@@ -109,9 +113,9 @@ func TestUpdateProduct(t *testing.T) {
 		t.Error(err)
 	}
 
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.Category{})
 
-	product, err := entity.NewProduct("Blusa", 9.99)
+	product, err := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
 
 	assert.NoError(t, err)
 
@@ -123,7 +127,7 @@ func TestUpdateProduct(t *testing.T) {
 
 	assert.NotEmpty(t, product.ID)
 	product.Name = "Blusa 2"
-	err = productDB.UpdateProduct(product)
+	err = productDB.UpdateProduct(product, nil)
 
 	productFound, err := productDB.FindProductByID(product.ID.String())
 	assert.NoError(t, err)
@@ -137,9 +141,9 @@ func TestDeleteProduct(t *testing.T) {
 		t.Error(err)
 	}
 
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.Category{})
 
-	product, err := entity.NewProduct("Blusa", 9.99)
+	product, err := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
 
 	assert.NoError(t, err)
 
@@ -158,7 +162,7 @@ func TestDeleteProduct(t *testing.T) {
 	assert.Equal(t, product.Name, productFound.Name)
 	assert.Equal(t, product.Price, productFound.Price)
 
-	err = productDB.DeleteProduct(product.ID.String())
+	err = productDB.DeleteProduct(product, nil)
 	assert.NoError(t, err)
 
 	_, err = productDB.FindProductByID(product.ID.String())
@@ -166,3 +170,310 @@ func TestDeleteProduct(t *testing.T) {
 	assert.Error(t, err)
 
 }
+
+func TestFindAllProductsByCategory(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	kitchen, _ := entity.NewCategory("Kitchen", nil)
+	db.Create(electronics)
+	db.Create(kitchen)
+
+	productDB := NewProductDB(db)
+
+	phone, _ := entity.NewProduct("Smartphone", entityPkg.MustParseMoney("999.9", "BRL"))
+	blender, _ := entity.NewProduct("Blender", entityPkg.MustParseMoney("199.9", "BRL"))
+	assert.NoError(t, productDB.CreateProduct(phone))
+	assert.NoError(t, productDB.CreateProduct(blender))
+	assert.NoError(t, productDB.UpdateProduct(phone, []entity.Category{*electronics}))
+	assert.NoError(t, productDB.UpdateProduct(blender, []entity.Category{*kitchen}))
+
+	products, _, err := productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, CategoryIDs: []string{electronics.ID.String()}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Smartphone", products[0].Name)
+	assert.Len(t, products[0].Categories, 1)
+
+	products, _, err = productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, CategoryIDs: []string{electronics.ID.String(), kitchen.ID.String()}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	assert.NoError(t, productDB.UpdateProduct(phone, []entity.Category{}))
+	found, err := productDB.FindProductByID(phone.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, found.Categories)
+}
+
+func TestFindAllProductsInStock(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+
+	productDB := NewProductDB(db)
+
+	available, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	available.Stock = 3
+	soldOut, _ := entity.NewProduct("Calça", entityPkg.MustParseMoney("19.99", "BRL"))
+	assert.NoError(t, productDB.CreateProduct(available))
+	assert.NoError(t, productDB.CreateProduct(soldOut))
+
+	inStock := true
+	products, _, err := productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, InStock: &inStock})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Blusa", products[0].Name)
+
+	inStock = false
+	products, _, err = productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, InStock: &inStock})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Calça", products[0].Name)
+
+	// UpdateProduct não pode sobrescrever o estoque
+	available.Stock = 100
+	available.Name = "Blusa 2"
+	assert.NoError(t, productDB.UpdateProduct(available, nil))
+	found, _ := productDB.FindProductByID(available.ID.String())
+	assert.Equal(t, "Blusa 2", found.Name)
+	assert.Equal(t, 3, found.Stock)
+}
+
+func TestFindAllProductsWithQuery(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+	productDB := NewProductDB(db)
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	for _, p := range []struct {
+		name    string
+		price   string
+		created time.Time
+	}{
+		{"Caneca 100%", "30.00", yesterday},
+		{"Caneca Azul", "20.00", time.Now()},
+		{"Camiseta", "20.00", time.Now()},
+		{"Boné", "50.00", time.Now()},
+	} {
+		product, _ := entity.NewProduct(p.name, entityPkg.MustParseMoney(p.price, "BRL"))
+		product.CreatedAt = p.created
+		assert.NoError(t, productDB.CreateProduct(product))
+	}
+
+	names := func(products []entity.Product) []string {
+		result := []string{}
+		for _, p := range products {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+
+	// sort=-price,name
+	products, total, err := productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10,
+		Sort: []ProductSort{{Field: "price", Desc: true}, {Field: "name"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, []string{"Boné", "Caneca 100%", "Camiseta", "Caneca Azul"}, names(products))
+
+	min := entityPkg.MustParseMoney("20", "BRL")
+	max := entityPkg.MustParseMoney("30", "BRL")
+	products, total, err = productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 1,
+		Sort: []ProductSort{{Field: "name"}}, PriceMin: &min, PriceMax: &max, NameContains: "CANECA"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, []string{"Caneca 100%"}, names(products))
+
+	// Curingas do LIKE são tratados como texto
+	products, _, err = productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, NameContains: "0%"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Caneca 100%"}, names(products))
+
+	after := time.Now().Add(-time.Hour)
+	_, total, err = productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, CreatedAfter: &after})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+
+	_, _, err = productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10, Sort: []ProductSort{{Field: "password"}}})
+	assert.Error(t, err)
+}
+
+func TestFindAllProductsAfterCursor(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+	productDB := NewProductDB(db)
+
+	// Produtos com a mesma data de criação são desempatados pelo id
+	created := time.Now().Add(-time.Hour)
+	for i := 1; i <= 5; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.MustParseMoney("1", "BRL"))
+		if i <= 3 {
+			product.CreatedAt = created
+		}
+		assert.NoError(t, productDB.CreateProduct(product))
+	}
+
+	for _, desc := range []bool{false, true} {
+		var seen []string
+		var after *cursor.Cursor
+		for {
+			products, _, err := productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 2, After: after,
+				Sort: []ProductSort{{Field: "created_at", Desc: desc}}})
+			assert.NoError(t, err)
+			if len(products) == 0 {
+				break
+			}
+			for _, p := range products {
+				seen = append(seen, p.ID.String())
+			}
+			last := products[len(products)-1]
+			after = &cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID.String(), Desc: desc}
+
+			// Um produto criado no meio da paginação não duplica nem pula itens
+			if len(seen) == 2 && !desc {
+				late, _ := entity.NewProduct("Late", entityPkg.MustParseMoney("1", "BRL"))
+				late.CreatedAt = created.Add(-time.Minute)
+				assert.NoError(t, productDB.CreateProduct(late))
+			}
+		}
+
+		unique := map[string]bool{}
+		for _, id := range seen {
+			unique[id] = true
+		}
+		assert.Len(t, unique, len(seen))
+		if !desc {
+			assert.Len(t, seen, 5)
+		}
+	}
+}
+
+func TestProductTrash(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+
+	productDB := NewProductDB(db)
+	category, _ := entity.NewCategory("Roupas", nil)
+	db.Create(category)
+
+	product, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	product.Categories = []entity.Category{*category}
+	assert.NoError(t, productDB.CreateProduct(product))
+	other, _ := entity.NewProduct("Calça", entityPkg.MustParseMoney("19.99", "BRL"))
+	assert.NoError(t, productDB.CreateProduct(other))
+
+	actor := entityPkg.NewID()
+	assert.NoError(t, productDB.DeleteProduct(product, &actor))
+	assert.ErrorIs(t, productDB.DeleteProduct(product, &actor), gorm.ErrRecordNotFound)
+
+	// Fora da listagem, mas na lixeira com quem removeu
+	products, total, err := productDB.FindAllProducts(ProductQuery{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, other.ID, products[0].ID)
+
+	trash, total, err := productDB.FindDeletedProducts(1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.True(t, trash[0].IsDeleted())
+	assert.Equal(t, &actor, trash[0].DeletedBy)
+
+	restored, err := productDB.RestoreProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, restored.IsDeleted())
+	assert.Nil(t, restored.DeletedBy)
+	assert.Len(t, restored.Categories, 1)
+
+	_, err = productDB.RestoreProduct(product.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Só produtos na lixeira podem ser expurgados
+	assert.ErrorIs(t, productDB.PurgeProduct(product.ID.String()), gorm.ErrRecordNotFound)
+	assert.NoError(t, productDB.DeleteProduct(restored, nil))
+	assert.NoError(t, productDB.PurgeProduct(product.ID.String()))
+
+	var count int64
+	db.Unscoped().Model(&entity.Product{}).Where("id = ?", product.ID).Count(&count)
+	assert.Zero(t, count)
+	db.Table("product_categories").Where("product_id = ?", product.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestPurgeDeletedProducts(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+
+	productDB := NewProductDB(db)
+	old, _ := entity.NewProduct("Antigo", entityPkg.MustParseMoney("1", "BRL"))
+	recent, _ := entity.NewProduct("Recente", entityPkg.MustParseMoney("1", "BRL"))
+	kept, _ := entity.NewProduct("Ativo", entityPkg.MustParseMoney("1", "BRL"))
+	for _, p := range []*entity.Product{old, recent, kept} {
+		assert.NoError(t, productDB.CreateProduct(p))
+	}
+	assert.NoError(t, productDB.DeleteProduct(old, nil))
+	assert.NoError(t, productDB.DeleteProduct(recent, nil))
+	db.Unscoped().Model(old).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := productDB.PurgeDeletedProducts(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var count int64
+	db.Unscoped().Model(&entity.Product{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestUpdateProductChecksVersion(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{}, &entity.StockMovement{})
+
+	productDB := NewProductDB(db)
+	product, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	first, _ := productDB.FindProductByID(product.ID.String())
+	second, _ := productDB.FindProductByID(product.ID.String())
+
+	first.Name = "Blusa azul"
+	assert.NoError(t, productDB.UpdateProduct(first, nil))
+	assert.Equal(t, int64(2), first.Version)
+
+	// A segunda edição foi feita sobre a versão antiga e não sobrescreve a
+	// primeira, nem mesmo as categorias
+	category, _ := entity.NewCategory("Roupas", nil)
+	db.Create(category)
+	second.Name = "Blusa verde"
+	assert.ErrorIs(t, productDB.UpdateProduct(second, []entity.Category{*category}), entity.ErrProductVersionConflict)
+
+	found, _ := productDB.FindProductByID(product.ID.String())
+	assert.Equal(t, "Blusa azul", found.Name)
+	assert.Equal(t, int64(2), found.Version)
+	assert.Empty(t, found.Categories)
+
+	assert.NoError(t, productDB.UpdateProduct(found, []entity.Category{*category}))
+	found, _ = productDB.FindProductByID(product.ID.String())
+	assert.Len(t, found.Categories, 1)
+	assert.Equal(t, int64(3), found.Version)
+
+	// Movimentações de estoque também mudam a versão
+	movement, _ := entity.NewStockMovement(product.ID, entity.StockMovementReceipt, 3, "", nil)
+	assert.NoError(t, NewStockDB(db).AdjustStock(movement))
+	found, _ = productDB.FindProductByID(product.ID.String())
+	assert.Equal(t, int64(4), found.Version)
+
+	missing, _ := entity.NewProduct("Outro", entityPkg.MustParseMoney("1", "BRL"))
+	assert.ErrorIs(t, productDB.UpdateProduct(missing, nil), gorm.ErrRecordNotFound)
+}
+
+func TestDeleteProductChecksVersion(t *testing.T) {
+	db := newTestDB(t, &entity.Product{}, &entity.Category{})
+
+	productDB := NewProductDB(db)
+	product, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	assert.NoError(t, productDB.CreateProduct(product))
+
+	stale, _ := productDB.FindProductByID(product.ID.String())
+	current, _ := productDB.FindProductByID(product.ID.String())
+	current.Name = "Blusa azul"
+	assert.NoError(t, productDB.UpdateProduct(current, nil))
+
+	// A edição feita no meio não vai para a lixeira junto com a versão antiga
+	assert.ErrorIs(t, productDB.DeleteProduct(stale, nil), entity.ErrProductVersionConflict)
+	found, err := productDB.FindProductByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, found.IsDeleted())
+
+	assert.NoError(t, productDB.DeleteProduct(current, nil))
+	assert.Equal(t, int64(3), current.Version)
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenDB struct {
+	DB *gorm.DB
+}
+
+func NewRefreshTokenDB(db *gorm.DB) *RefreshTokenDB {
+	return &RefreshTokenDB{
+		DB: db,
+	}
+}
+
+func (rdb *RefreshTokenDB) CreateRefreshToken(token *entity.RefreshToken) error {
+	return rdb.DB.Create(token).Error
+}
+
+func (rdb *RefreshTokenDB) FindRefreshTokenByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := rdb.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed flags the token as consumed. The update only matches
+// tokens that were not used yet, so when two requests race with the same
+// token only one of them gets true back.
+func (rdb *RefreshTokenDB) MarkRefreshTokenUsed(id string) (bool, error) {
+	result := rdb.DB.Model(&entity.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (rdb *RefreshTokenDB) RevokeRefreshTokenFamily(familyID string) error {
+	return rdb.DB.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).
+		Error
+}
+
+func (rdb *RefreshTokenDB) RevokeUserRefreshTokens(userID string) error {
+	return rdb.DB.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).
+		Error
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRefreshTokenDB(t *testing.T) *RefreshTokenDB {
+	db := newTestDB(t, &entity.RefreshToken{})
+	return NewRefreshTokenDB(db)
+}
+
+func TestCreateAndFindRefreshToken(t *testing.T) {
+	tokenDB := setupRefreshTokenDB(t)
+
+	token, plain, err := entity.NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.CreateRefreshToken(token))
+
+	found, err := tokenDB.FindRefreshTokenByHash(entityPkg.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, found.ID)
+	assert.Equal(t, token.FamilyID, found.FamilyID)
+
+	_, err = tokenDB.FindRefreshTokenByHash(entityPkg.HashToken("unknown"))
+	assert.Error(t, err)
+}
+
+func TestMarkRefreshTokenUsed(t *testing.T) {
+	tokenDB := setupRefreshTokenDB(t)
+
+	token, plain, _ := entity.NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+	assert.NoError(t, tokenDB.CreateRefreshToken(token))
+
+	ok, err := tokenDB.MarkRefreshTokenUsed(token.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = tokenDB.MarkRefreshTokenUsed(token.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	found, err := tokenDB.FindRefreshTokenByHash(entityPkg.HashToken(plain))
+	assert.NoError(t, err)
+	assert.NotNil(t, found.UsedAt)
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	tokenDB := setupRefreshTokenDB(t)
+
+	userID := entityPkg.NewID()
+	familyID := entityPkg.NewID()
+	first, firstPlain, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	second, secondPlain, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	other, otherPlain, _ := entity.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+	assert.NoError(t, tokenDB.CreateRefreshToken(first))
+	assert.NoError(t, tokenDB.CreateRefreshToken(second))
+	assert.NoError(t, tokenDB.CreateRefreshToken(other))
+
+	assert.NoError(t, tokenDB.RevokeRefreshTokenFamily(familyID.String()))
+
+	for _, plain := range []string{firstPlain, secondPlain} {
+		found, err := tokenDB.FindRefreshTokenByHash(entityPkg.HashToken(plain))
+		assert.NoError(t, err)
+		assert.Equal(t, entity.ErrRefreshTokenRevoked, found.Validate())
+	}
+
+	found, err := tokenDB.FindRefreshTokenByHash(entityPkg.HashToken(otherPlain))
+	assert.NoError(t, err)
+	assert.Nil(t, found.Validate())
+}
@@ -31,19 +31,100 @@ func (rdb *RoleDB) FindRoleByName(name string) (*entity.Role, error) {
 }
 
 func (rdb *RoleDB) RoleExists(name string) (bool, error) {
-        var count int64
-        err := rdb.DB.Model(&entity.Role{}).Where("name = ?", name).Count(&count).Error
-        if err != nil {
-                return false, err
-        }
-        return count > 0, nil
+	var count int64
+	err := rdb.DB.Model(&entity.Role{}).Where("name = ?", name).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
 func (rdb *RoleDB) FindRoleByID(id string) (*entity.Role, error) {
-        var role entity.Role
-        err := rdb.DB.First(&role, "id = ?", id).Error
-        if err != nil {
-                return nil, err
-        }
-        return &role, nil
+	var role entity.Role
+	err := rdb.DB.Preload("Permissions", orderPermissions).First(&role, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindAllRoles returns every role with its permissions, ordered by name.
+func (rdb *RoleDB) FindAllRoles() ([]entity.Role, error) {
+	var roles []entity.Role
+	err := rdb.DB.Preload("Permissions", orderPermissions).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// UpdateRole saves the name of the role. Permissions change through
+// SetRolePermissions.
+func (rdb *RoleDB) UpdateRole(role *entity.Role) error {
+	return rdb.DB.Model(role).Update("name", role.Name).Error
+}
+
+// DeleteRole removes a role that no user has anymore.
+func (rdb *RoleDB) DeleteRole(id string) error {
+	return rdb.DB.Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&entity.User{}).Where("role_id = ?", id).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return entity.ErrRoleInUse
+		}
+
+		if err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&entity.Role{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// SetRolePermissions replaces the permissions of the role.
+func (rdb *RoleDB) SetRolePermissions(role *entity.Role, permissions []entity.Permission) error {
+	err := rdb.DB.Model(role).Association("Permissions").Replace(permissions)
+	if err != nil {
+		return err
+	}
+	role.Permissions = permissions
+	return nil
+}
+
+// FindRolePermissions returns the names of the permissions granted to the
+// role. Unknown roles have none.
+func (rdb *RoleDB) FindRolePermissions(roleID string) ([]string, error) {
+	var names []string
+	err := rdb.DB.Model(&entity.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", roleID).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).
+		Error
+	return names, err
+}
+
+func (rdb *RoleDB) SetRoleRequireMFA(id string, required bool) error {
+	return rdb.DB.Model(&entity.Role{}).Where("id = ?", id).Update("require_mfa", required).Error
+}
+
+// RoleRequiresMFA reports whether the role asks its users for two-factor
+// authentication. Unknown roles don't.
+func (rdb *RoleDB) RoleRequiresMFA(roleID string) (bool, error) {
+	var required []bool
+	err := rdb.DB.Model(&entity.Role{}).Where("id = ?", roleID).Pluck("require_mfa", &required).Error
+	if err != nil || len(required) == 0 {
+		return false, err
+	}
+	return required[0], nil
+}
+
+func orderPermissions(db *gorm.DB) *gorm.DB {
+	return db.Order("permissions.name")
 }
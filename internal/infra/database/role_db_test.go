@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
 	"github.com/stretchr/testify/assert"
 	_ "github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -80,3 +81,100 @@ func TestFindRoleByID(t *testing.T) {
 	assert.Equal(t, role.ID, found.ID)
 	assert.Equal(t, role.Name, found.Name)
 }
+
+func setupRoleDB(t *testing.T) (*gorm.DB, *RoleDB, *PermissionDB) {
+	db := newTestDB(t, &entity.Role{}, &entity.Permission{}, &entity.User{})
+	return db, NewRoleDB(db), NewPermissionDB(db)
+}
+
+func TestSetRolePermissions(t *testing.T) {
+	_, roleDB, permissionDB := setupRoleDB(t)
+
+	role, _ := entity.NewRole("support")
+	assert.NoError(t, roleDB.CreateRole(role))
+
+	for _, name := range []string{"user:read", "order:read", "order:write"} {
+		permission, _ := entity.NewPermission(name, "")
+		assert.NoError(t, permissionDB.CreatePermission(permission))
+	}
+
+	permissions, err := permissionDB.FindPermissionsByNames([]string{"order:read", "user:read", "missing:read"})
+	assert.NoError(t, err)
+	assert.Len(t, permissions, 2)
+
+	assert.NoError(t, roleDB.SetRolePermissions(role, permissions))
+
+	names, err := roleDB.FindRolePermissions(role.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order:read", "user:read"}, names)
+
+	found, err := roleDB.FindRoleByID(role.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Permissions, 2)
+
+	// Trocar a lista remove o que ficou de fora
+	permissions, _ = permissionDB.FindPermissionsByNames([]string{"order:write"})
+	assert.NoError(t, roleDB.SetRolePermissions(role, permissions))
+	names, err = roleDB.FindRolePermissions(role.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order:write"}, names)
+
+	names, err = roleDB.FindRolePermissions(entityPkg.NewID().String())
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestUpdateAndDeleteRole(t *testing.T) {
+	db, roleDB, permissionDB := setupRoleDB(t)
+
+	role, _ := entity.NewRole("support")
+	assert.NoError(t, roleDB.CreateRole(role))
+	permission, _ := entity.NewPermission("user:read", "")
+	assert.NoError(t, permissionDB.CreatePermission(permission))
+	assert.NoError(t, roleDB.SetRolePermissions(role, []entity.Permission{*permission}))
+
+	role.Name = "helpdesk"
+	assert.NoError(t, roleDB.UpdateRole(role))
+	roles, err := roleDB.FindAllRoles()
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	assert.Equal(t, "helpdesk", roles[0].Name)
+	assert.Len(t, roles[0].Permissions, 1)
+
+	user, _ := entity.NewUser("Mateus", "m@gmail.com", "123456789", role.ID)
+	assert.NoError(t, db.Create(user).Error)
+	assert.ErrorIs(t, roleDB.DeleteRole(role.ID.String()), entity.ErrRoleInUse)
+
+	assert.NoError(t, db.Delete(user).Error)
+	assert.NoError(t, roleDB.DeleteRole(role.ID.String()))
+
+	var links int64
+	db.Table("role_permissions").Where("role_id = ?", role.ID).Count(&links)
+	assert.Zero(t, links)
+
+	assert.ErrorIs(t, roleDB.DeleteRole(role.ID.String()), gorm.ErrRecordNotFound)
+}
+
+func TestSetRoleRequireMFA(t *testing.T) {
+	_, roleDB, _ := setupRoleDB(t)
+
+	role, _ := entity.NewRole("support")
+	assert.NoError(t, roleDB.CreateRole(role))
+
+	required, err := roleDB.RoleRequiresMFA(role.ID.String())
+	assert.NoError(t, err)
+	assert.False(t, required)
+
+	assert.NoError(t, roleDB.SetRoleRequireMFA(role.ID.String(), true))
+	required, err = roleDB.RoleRequiresMFA(role.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, required)
+
+	found, err := roleDB.FindRoleByID(role.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, found.RequireMFA)
+
+	required, err = roleDB.RoleRequiresMFA(entityPkg.NewID().String())
+	assert.NoError(t, err)
+	assert.False(t, required)
+}
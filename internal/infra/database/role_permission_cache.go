@@ -0,0 +1,102 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+)
+
+// RolePermissionCache keeps the permissions of each role, and whether it
+// requires MFA, in memory so that the permission middleware doesn't hit the
+// database on every request.
+// Changes made through the cache are seen right away; ttl bounds how long a
+// change made by another instance takes to be seen. The other RoleInterface
+// methods go straight to the store.
+type RolePermissionCache struct {
+	RoleInterface
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	roles map[string]cachedRole
+}
+
+type cachedRole struct {
+	names      []string
+	requireMFA bool
+	until      time.Time
+}
+
+func NewRolePermissionCache(store RoleInterface, ttl time.Duration) *RolePermissionCache {
+	return &RolePermissionCache{
+		RoleInterface: store,
+		ttl:           ttl,
+		roles:         make(map[string]cachedRole),
+	}
+}
+
+func (c *RolePermissionCache) FindRolePermissions(roleID string) ([]string, error) {
+	cached, err := c.load(roleID)
+	return cached.names, err
+}
+
+func (c *RolePermissionCache) RoleRequiresMFA(roleID string) (bool, error) {
+	cached, err := c.load(roleID)
+	return cached.requireMFA, err
+}
+
+func (c *RolePermissionCache) load(roleID string) (cachedRole, error) {
+	now := time.Now()
+
+	c.mu.RLock()
+	cached, ok := c.roles[roleID]
+	c.mu.RUnlock()
+	if ok && now.Before(cached.until) {
+		return cached, nil
+	}
+
+	names, err := c.RoleInterface.FindRolePermissions(roleID)
+	if err != nil {
+		return cachedRole{}, err
+	}
+	requireMFA, err := c.RoleInterface.RoleRequiresMFA(roleID)
+	if err != nil {
+		return cachedRole{}, err
+	}
+
+	cached = cachedRole{names: names, requireMFA: requireMFA, until: now.Add(c.ttl)}
+	c.mu.Lock()
+	c.roles[roleID] = cached
+	c.mu.Unlock()
+	return cached, nil
+}
+
+func (c *RolePermissionCache) SetRolePermissions(role *entity.Role, permissions []entity.Permission) error {
+	if err := c.RoleInterface.SetRolePermissions(role, permissions); err != nil {
+		return err
+	}
+	c.forget(role.ID.String())
+	return nil
+}
+
+func (c *RolePermissionCache) SetRoleRequireMFA(id string, required bool) error {
+	if err := c.RoleInterface.SetRoleRequireMFA(id, required); err != nil {
+		return err
+	}
+	c.forget(id)
+	return nil
+}
+
+func (c *RolePermissionCache) DeleteRole(id string) error {
+	if err := c.RoleInterface.DeleteRole(id); err != nil {
+		return err
+	}
+	c.forget(id)
+	return nil
+}
+
+func (c *RolePermissionCache) forget(roleID string) {
+	c.mu.Lock()
+	delete(c.roles, roleID)
+	c.mu.Unlock()
+}
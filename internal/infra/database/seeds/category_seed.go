@@ -0,0 +1,74 @@
+package seed
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// Categorias filhas e os seus pais; as demais ficam na raiz
+var categoryParents = map[string]string{
+	"Computing":     "Electronics",
+	"Gaming":        "Electronics",
+	"Audio":         "Electronics",
+	"Kitchen":       "Home Appliances",
+	"Cleaning":      "Home Appliances",
+	"Personal Care": "Home Appliances",
+}
+
+func SeedCategories(db *gorm.DB) {
+	categoryDB := database.NewCategoryDB(db)
+
+	// Verifica se já existem categorias no banco
+	var count int64
+	if err := db.Model(&entity.Category{}).Count(&count).Error; err != nil {
+		log.Printf("Erro ao verificar categorias existentes: %v\n", err)
+		return
+	}
+
+	if count > 0 {
+		fmt.Println("Já existem categorias no banco de dados. Pulando seed...")
+		return
+	}
+
+	created := make(map[string]*entity.Category)
+
+	// Cria primeiro as categorias raiz para que os filhos encontrem o pai
+	for _, onlyRoots := range []bool{true, false} {
+		for _, name := range productCategories {
+			parentName, hasParent := categoryParents[name]
+			if hasParent == onlyRoots {
+				continue
+			}
+
+			var category *entity.Category
+			var err error
+			if hasParent {
+				parent, ok := created[parentName]
+				if !ok {
+					log.Printf("Categoria pai '%s' não encontrada para '%s'\n", parentName, name)
+					continue
+				}
+				category, err = entity.NewCategory(name, &parent.ID)
+			} else {
+				category, err = entity.NewCategory(name, nil)
+			}
+
+			if err != nil {
+				log.Printf("Erro ao criar categoria '%s': %v\n", name, err)
+				continue
+			}
+
+			if err := categoryDB.CreateCategory(category); err != nil {
+				log.Printf("Erro ao salvar categoria '%s' no banco: %v\n", name, err)
+				continue
+			}
+
+			created[name] = category
+			fmt.Printf("Categoria '%s' criada com sucesso!\n", name)
+		}
+	}
+}
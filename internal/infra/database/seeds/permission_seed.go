@@ -0,0 +1,86 @@
+package seed
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// Permissões usadas pelas rotas e as roles que as recebem quando são criadas.
+// A role admin sempre recebe todas.
+var permissionCatalog = []struct {
+	name        string
+	description string
+	roles       []string
+}{
+	{entity.PermissionProductWrite, "Create, edit, delete and restore products", []string{entity.RoleManager}},
+	{entity.PermissionProductPurge, "Permanently delete products from the trash", nil},
+	{entity.PermissionStockRead, "See stock levels and movements", []string{entity.RoleManager}},
+	{entity.PermissionStockWrite, "Adjust the stock of products", []string{entity.RoleManager}},
+	{entity.PermissionCategoryWrite, "Create, edit and delete categories", []string{entity.RoleManager}},
+	{entity.PermissionOrderRead, "See the orders of every user", []string{entity.RoleManager}},
+	{entity.PermissionOrderWrite, "Move orders through their statuses", []string{entity.RoleManager}},
+	{entity.PermissionUserRead, "See user accounts", nil},
+	{entity.PermissionUserWrite, "Change roles, disable and delete user accounts", nil},
+	{entity.PermissionRoleRead, "See roles and their permissions", nil},
+	{entity.PermissionRoleWrite, "Create, edit and delete roles and their permissions", nil},
+}
+
+// SeedPermissions cria as permissões que ainda não existem. Permissões novas
+// são concedidas às roles padrão; as já existentes mantêm as concessões
+// feitas pelos administradores.
+func SeedPermissions(db *gorm.DB) {
+	permissionDB := database.NewPermissionDB(db)
+	roleDB := database.NewRoleDB(db)
+
+	admin, err := roleDB.FindRoleByName(entity.RoleAdmin)
+	if err != nil {
+		log.Printf("Erro ao buscar a role '%s': %v\n", entity.RoleAdmin, err)
+		return
+	}
+
+	for _, p := range permissionCatalog {
+		existing, err := permissionDB.FindPermissionsByNames([]string{p.name})
+		if err != nil {
+			log.Printf("Erro ao verificar a permissão '%s': %v\n", p.name, err)
+			continue
+		}
+
+		var permission *entity.Permission
+		var roles []string
+		if len(existing) > 0 {
+			permission = &existing[0]
+		} else {
+			permission, err = entity.NewPermission(p.name, p.description)
+			if err != nil {
+				log.Printf("Erro ao criar permissão '%s': %v\n", p.name, err)
+				continue
+			}
+			if err := permissionDB.CreatePermission(permission); err != nil {
+				log.Printf("Erro ao salvar permissão '%s' no banco: %v\n", p.name, err)
+				continue
+			}
+			roles = p.roles
+			fmt.Printf("Permissão '%s' criada com sucesso!\n", p.name)
+		}
+
+		// A admin recebe de novo a cada execução, o que não duplica nada
+		if err := db.Model(admin).Association("Permissions").Append(permission); err != nil {
+			log.Printf("Erro ao conceder '%s' à role '%s': %v\n", p.name, admin.Name, err)
+		}
+
+		for _, roleName := range roles {
+			role, err := roleDB.FindRoleByName(roleName)
+			if err != nil {
+				log.Printf("Role '%s' não encontrada para a permissão '%s'\n", roleName, p.name)
+				continue
+			}
+			if err := db.Model(role).Association("Permissions").Append(permission); err != nil {
+				log.Printf("Erro ao conceder '%s' à role '%s': %v\n", p.name, roleName, err)
+			}
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
 	"gorm.io/gorm"
 )
 
@@ -27,6 +28,7 @@ var productCategories = []string{
 
 func SeedProducts(db *gorm.DB) {
 	productDB := database.NewProductDB(db)
+	categoryDB := database.NewCategoryDB(db)
 
 	// Verifica se já existem produtos no banco
 	var count int64
@@ -43,6 +45,16 @@ func SeedProducts(db *gorm.DB) {
 	// Inicializa o gerador de números aleatórios
 	rand.Seed(time.Now().UnixNano())
 
+	// Indexa as categorias pelo nome para associar aos produtos
+	categoriesByName := make(map[string]entity.Category)
+	categories, err := categoryDB.FindAllCategories()
+	if err != nil {
+		log.Printf("Erro ao buscar categorias: %v\n", err)
+	}
+	for _, category := range categories {
+		categoriesByName[category.Name] = category
+	}
+
 	// Cria 20 produtos aleatórios
 	for i := 0; i < 20; i++ {
 		// Gera um nome aleatório
@@ -50,8 +62,8 @@ func SeedProducts(db *gorm.DB) {
 		categoryIndex := rand.Intn(len(productCategories))
 		name := fmt.Sprintf("%s %s", productNames[nameIndex], productCategories[categoryIndex])
 
-		// Gera um preço aleatório entre 100 e 10000
-		price := rand.Float64()*9900 + 100
+		// Gera um preço aleatório entre 100 e 10000, em centavos
+		price := entityPkg.NewMoney(rand.Int63n(990000)+10000, entityPkg.DefaultCurrency)
 
 		// Cria o produto
 		product, err := entity.NewProduct(name, price)
@@ -60,6 +72,10 @@ func SeedProducts(db *gorm.DB) {
 			continue
 		}
 
+		if category, ok := categoriesByName[productCategories[categoryIndex]]; ok {
+			product.Categories = []entity.Category{category}
+		}
+
 		// Salva no banco
 		if err := productDB.CreateProduct(product); err != nil {
 			log.Printf("Erro ao salvar produto '%s' no banco: %v\n", name, err)
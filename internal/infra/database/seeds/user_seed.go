@@ -3,6 +3,7 @@ package seed
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
@@ -45,11 +46,12 @@ func SeedUsers(db *gorm.DB) {
 			log.Printf("Role não encontrada para o usuário %s\n", u.email)
 			continue
 		}
-		user, err := entity.NewUser(u.name, u.email, "1234", u.role.ID)
+		user, err := entity.NewUser(u.name, u.email, "12345678", u.role.ID)
 		if err != nil {
 			log.Printf("Erro ao criar usuário '%s': %v\n", u.email, err)
 			continue
 		}
+		user.VerifyEmail(time.Now())
 		if err := userDB.CreateUser(user); err != nil {
 			log.Printf("Erro ao salvar usuário '%s': %v\n", u.email, err)
 		} else {
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"gorm.io/gorm"
+)
+
+type StockDB struct {
+	DB *gorm.DB
+}
+
+func NewStockDB(db *gorm.DB) *StockDB {
+	return &StockDB{
+		DB: db,
+	}
+}
+
+// AdjustStock applies the movement to the product stock and appends it to the
+// ledger in a single transaction. The stock is changed with a conditional
+// UPDATE instead of read-modify-write, so concurrent adjustments never lose
+// updates nor take the stock below zero.
+func (sdb *StockDB) AdjustStock(movement *entity.StockMovement) error {
+	return sdb.DB.Transaction(func(tx *gorm.DB) error {
+		return adjustStock(tx, movement)
+	})
+}
+
+// adjustStock runs the adjustment inside an existing transaction.
+func adjustStock(tx *gorm.DB, movement *entity.StockMovement) error {
+	result := tx.Model(&entity.Product{}).
+		Where("id = ? AND stock + ? >= 0", movement.ProductID.String(), movement.Quantity).
+		Updates(map[string]interface{}{
+			"stock":   gorm.Expr("stock + ?", movement.Quantity),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&entity.Product{}).Where("id = ?", movement.ProductID.String()).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return entity.ErrInsufficientStock
+	}
+
+	var product entity.Product
+	if err := tx.Select("stock").First(&product, "id = ?", movement.ProductID.String()).Error; err != nil {
+		return err
+	}
+	movement.BalanceAfter = product.Stock
+
+	return tx.Create(movement).Error
+}
+
+func (sdb *StockDB) FindStockMovements(productID string, page, limit int) ([]entity.StockMovement, error) {
+	var movements []entity.StockMovement
+	err := sdb.DB.
+		Where("product_id = ?", productID).
+		Order("created_at desc").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&movements).
+		Error
+	return movements, err
+}
+
+// FindLowStockProducts returns the products whose stock reached their
+// threshold, the emptiest first.
+func (sdb *StockDB) FindLowStockProducts() ([]entity.Product, error) {
+	var products []entity.Product
+	err := sdb.DB.
+		Where("low_stock_threshold > 0 AND stock <= low_stock_threshold").
+		Order("stock asc").
+		Find(&products).
+		Error
+	return products, err
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupStockDB(t *testing.T) (*gorm.DB, *StockDB, *entity.Product) {
+	// Banco em arquivo para que as goroutines compartilhem os mesmos dados
+	dsn := filepath.Join(t.TempDir(), "stock.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db := openTestDB(t, dsn, &entity.Product{}, &entity.Category{}, &entity.StockMovement{})
+	product, _ := entity.NewProduct("Blusa", entityPkg.MustParseMoney("9.99", "BRL"))
+	db.Create(product)
+	return db, NewStockDB(db), product
+}
+
+func TestAdjustStock(t *testing.T) {
+	_, stockDB, product := setupStockDB(t)
+	actorID := entityPkg.NewID()
+
+	receipt, _ := entity.NewStockMovement(product.ID, entity.StockMovementReceipt, 10, "", &actorID)
+	assert.NoError(t, stockDB.AdjustStock(receipt))
+	assert.Equal(t, 10, receipt.BalanceAfter)
+
+	sale, _ := entity.NewStockMovement(product.ID, entity.StockMovementSale, 4, "", &actorID)
+	assert.NoError(t, stockDB.AdjustStock(sale))
+	assert.Equal(t, 6, sale.BalanceAfter)
+
+	tooMuch, _ := entity.NewStockMovement(product.ID, entity.StockMovementSale, 7, "", &actorID)
+	assert.Equal(t, entity.ErrInsufficientStock, stockDB.AdjustStock(tooMuch))
+
+	movements, err := stockDB.FindStockMovements(product.ID.String(), 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, movements, 2)
+
+	unknown, _ := entity.NewStockMovement(entityPkg.NewID(), entity.StockMovementReceipt, 1, "", nil)
+	assert.ErrorIs(t, stockDB.AdjustStock(unknown), gorm.ErrRecordNotFound)
+}
+
+func TestAdjustStockConcurrently(t *testing.T) {
+	db, stockDB, product := setupStockDB(t)
+
+	receipt, _ := entity.NewStockMovement(product.ID, entity.StockMovementReceipt, 10, "", nil)
+	assert.NoError(t, stockDB.AdjustStock(receipt))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sale, _ := entity.NewStockMovement(product.ID, entity.StockMovementSale, 1, "", nil)
+			if stockDB.AdjustStock(sale) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var found entity.Product
+	db.First(&found, "id = ?", product.ID)
+	assert.Equal(t, 10, succeeded)
+	assert.Equal(t, 0, found.Stock)
+
+	var movements int64
+	db.Model(&entity.StockMovement{}).Where("product_id = ?", product.ID).Count(&movements)
+	assert.Equal(t, int64(11), movements)
+}
+
+func TestFindLowStockProducts(t *testing.T) {
+	db, stockDB, product := setupStockDB(t)
+
+	product.LowStockThreshold = 5
+	db.Save(product)
+
+	other, _ := entity.NewProduct("Calça", entityPkg.MustParseMoney("19.99", "BRL"))
+	db.Create(other)
+
+	products, err := stockDB.FindLowStockProducts()
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, product.ID, products[0].ID)
+
+	receipt, _ := entity.NewStockMovement(product.ID, entity.StockMovementReceipt, 6, "", nil)
+	assert.NoError(t, stockDB.AdjustStock(receipt))
+
+	products, err = stockDB.FindLowStockProducts()
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
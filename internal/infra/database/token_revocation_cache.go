@@ -0,0 +1,143 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+)
+
+// TokenRevocationCache keeps revocation lookups in memory so that the
+// middleware doesn't hit the database on every authenticated request.
+// Revoked JTIs are kept until the token expires; negative lookups and user
+// cut-offs are cached for ttl, which bounds how long a revocation made by
+// another instance takes to be seen.
+type TokenRevocationCache struct {
+	store TokenRevocationInterface
+	ttl   time.Duration
+
+	mu        sync.RWMutex
+	tokens    map[string]cachedRevocation
+	cutoffs   map[string]cachedCutoff
+	lastEvict time.Time
+}
+
+type cachedRevocation struct {
+	revoked bool
+	until   time.Time
+}
+
+type cachedCutoff struct {
+	before time.Time
+	until  time.Time
+}
+
+func NewTokenRevocationCache(store TokenRevocationInterface, ttl time.Duration) *TokenRevocationCache {
+	return &TokenRevocationCache{
+		store:   store,
+		ttl:     ttl,
+		tokens:  make(map[string]cachedRevocation),
+		cutoffs: make(map[string]cachedCutoff),
+	}
+}
+
+func (c *TokenRevocationCache) RevokeToken(token *entity.RevokedToken) error {
+	if err := c.store.RevokeToken(token); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.tokens[token.JTI] = cachedRevocation{revoked: true, until: token.ExpiresAt}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *TokenRevocationCache) ConsumeToken(token *entity.RevokedToken) (bool, error) {
+	consumed, err := c.store.ConsumeToken(token)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.tokens[token.JTI] = cachedRevocation{revoked: true, until: token.ExpiresAt}
+	c.mu.Unlock()
+	return consumed, nil
+}
+
+func (c *TokenRevocationCache) IsTokenRevoked(jti string) (bool, error) {
+	now := time.Now()
+
+	c.mu.RLock()
+	cached, ok := c.tokens[jti]
+	c.mu.RUnlock()
+	if ok && now.Before(cached.until) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := c.store.IsTokenRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.evictExpired(now)
+	c.tokens[jti] = cachedRevocation{revoked: revoked, until: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return revoked, nil
+}
+
+func (c *TokenRevocationCache) RevokeUserTokens(userID string, before time.Time) error {
+	if err := c.store.RevokeUserTokens(userID, before); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.cutoffs[userID] = cachedCutoff{before: before, until: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *TokenRevocationCache) FindUserTokensRevokedBefore(userID string) (time.Time, error) {
+	now := time.Now()
+
+	c.mu.RLock()
+	cached, ok := c.cutoffs[userID]
+	c.mu.RUnlock()
+	if ok && now.Before(cached.until) {
+		return cached.before, nil
+	}
+
+	before, err := c.store.FindUserTokensRevokedBefore(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c.mu.Lock()
+	c.cutoffs[userID] = cachedCutoff{before: before, until: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return before, nil
+}
+
+func (c *TokenRevocationCache) DeleteExpiredRevokedTokens() error {
+	return c.store.DeleteExpiredRevokedTokens()
+}
+
+// evictExpired drops stale entries at most once per ttl; callers must hold
+// the write lock.
+func (c *TokenRevocationCache) evictExpired(now time.Time) {
+	if now.Sub(c.lastEvict) < c.ttl {
+		return
+	}
+	c.lastEvict = now
+
+	for jti, cached := range c.tokens {
+		if now.After(cached.until) {
+			delete(c.tokens, jti)
+		}
+	}
+	for userID, cached := range c.cutoffs {
+		if now.After(cached.until) {
+			delete(c.cutoffs, userID)
+		}
+	}
+}
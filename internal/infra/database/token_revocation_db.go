@@ -0,0 +1,90 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRevocationDB struct {
+	DB *gorm.DB
+}
+
+func NewTokenRevocationDB(db *gorm.DB) *TokenRevocationDB {
+	return &TokenRevocationDB{
+		DB: db,
+	}
+}
+
+func (tdb *TokenRevocationDB) RevokeToken(token *entity.RevokedToken) error {
+	return tdb.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// ConsumeToken revokes a single-use token, returning false when it was
+// revoked already, so only one of concurrent uses succeeds.
+func (tdb *TokenRevocationDB) ConsumeToken(token *entity.RevokedToken) (bool, error) {
+	result := tdb.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(token)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (tdb *TokenRevocationDB) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := tdb.DB.Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeUserTokens invalidates every token of the user issued before the
+// given instant. The API keys created before it are revoked too, so their
+// listing shows what the cut-off enforces.
+func (tdb *TokenRevocationDB) RevokeUserTokens(userID string, before time.Time) error {
+	id, err := entityPkg.ParseID(userID)
+	if err != nil {
+		return err
+	}
+
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		revocation := entity.UserTokenRevocation{UserID: id, RevokedBefore: before}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"revoked_before"}),
+		}).Create(&revocation).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL AND created_at < ?", userID, before).
+			Update("revoked_at", before).
+			Error
+	})
+}
+
+// FindUserTokensRevokedBefore returns the cut-off for the user's tokens, or
+// the zero time when none was set.
+func (tdb *TokenRevocationDB) FindUserTokensRevokedBefore(userID string) (time.Time, error) {
+	var revocation entity.UserTokenRevocation
+	err := tdb.DB.First(&revocation, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return revocation.RevokedBefore, nil
+}
+
+// DeleteExpiredRevokedTokens removes entries whose token would be rejected
+// anyway because it expired.
+func (tdb *TokenRevocationDB) DeleteExpiredRevokedTokens() error {
+	return tdb.DB.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error
+}
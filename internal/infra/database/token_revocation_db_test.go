@@ -0,0 +1,137 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTokenRevocationDB(t *testing.T) *TokenRevocationDB {
+	db := newTestDB(t, &entity.RevokedToken{}, &entity.UserTokenRevocation{}, &entity.Permission{}, &entity.APIKey{})
+	return NewTokenRevocationDB(db)
+}
+
+func TestRevokeToken(t *testing.T) {
+	revocationDB := setupTokenRevocationDB(t)
+
+	jti := entityPkg.NewID().String()
+	revoked, err := revocationDB.IsTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	token := entity.NewRevokedToken(jti, entityPkg.NewID(), time.Now().Add(time.Hour))
+	assert.NoError(t, revocationDB.RevokeToken(token))
+	// Revogar duas vezes não deve falhar
+	assert.NoError(t, revocationDB.RevokeToken(token))
+
+	revoked, err = revocationDB.IsTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestConsumeToken(t *testing.T) {
+	revocationDB := setupTokenRevocationDB(t)
+	token := entity.NewRevokedToken(entityPkg.NewID().String(), entityPkg.NewID(), time.Now().Add(time.Hour))
+
+	consumed, err := revocationDB.ConsumeToken(token)
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+
+	// Um token de uso único só pode ser usado uma vez
+	consumed, err = revocationDB.ConsumeToken(token)
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+
+	revoked, err := revocationDB.IsTokenRevoked(token.JTI)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	revocationDB := setupTokenRevocationDB(t)
+	userID := entityPkg.NewID().String()
+
+	before, err := revocationDB.FindUserTokensRevokedBefore(userID)
+	assert.NoError(t, err)
+	assert.True(t, before.IsZero())
+
+	first := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, revocationDB.RevokeUserTokens(userID, first))
+
+	second := time.Now().Truncate(time.Second)
+	assert.NoError(t, revocationDB.RevokeUserTokens(userID, second))
+
+	before, err = revocationDB.FindUserTokensRevokedBefore(userID)
+	assert.NoError(t, err)
+	assert.True(t, second.Equal(before))
+}
+
+func TestRevokeUserTokensRevokesAPIKeys(t *testing.T) {
+	revocationDB := setupTokenRevocationDB(t)
+	userID := entityPkg.NewID()
+
+	old, _, _ := entity.NewAPIKey(userID, "old", nil, nil)
+	old.CreatedAt = time.Now().Add(-time.Hour)
+	other, _, _ := entity.NewAPIKey(entityPkg.NewID(), "other", nil, nil)
+	other.CreatedAt = old.CreatedAt
+	assert.NoError(t, revocationDB.DB.Create(old).Error)
+	assert.NoError(t, revocationDB.DB.Create(other).Error)
+
+	before := time.Now()
+	assert.NoError(t, revocationDB.RevokeUserTokens(userID.String(), before))
+
+	// Chaves criadas depois do corte continuam valendo
+	recent, _, _ := entity.NewAPIKey(userID, "recent", nil, nil)
+	assert.NoError(t, revocationDB.DB.Create(recent).Error)
+
+	for _, tc := range []struct {
+		key     *entity.APIKey
+		revoked bool
+	}{{old, true}, {other, false}, {recent, false}} {
+		var found entity.APIKey
+		assert.NoError(t, revocationDB.DB.First(&found, "id = ?", tc.key.ID).Error)
+		assert.Equal(t, tc.revoked, found.RevokedAt != nil, tc.key.Name)
+	}
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	revocationDB := setupTokenRevocationDB(t)
+
+	expired := entity.NewRevokedToken(entityPkg.NewID().String(), entityPkg.NewID(), time.Now().Add(-time.Minute))
+	active := entity.NewRevokedToken(entityPkg.NewID().String(), entityPkg.NewID(), time.Now().Add(time.Hour))
+	assert.NoError(t, revocationDB.RevokeToken(expired))
+	assert.NoError(t, revocationDB.RevokeToken(active))
+
+	assert.NoError(t, revocationDB.DeleteExpiredRevokedTokens())
+
+	revoked, _ := revocationDB.IsTokenRevoked(expired.JTI)
+	assert.False(t, revoked)
+	revoked, _ = revocationDB.IsTokenRevoked(active.JTI)
+	assert.True(t, revoked)
+}
+
+func TestTokenRevocationCache(t *testing.T) {
+	revocationDB := setupTokenRevocationDB(t)
+	cache := NewTokenRevocationCache(revocationDB, time.Minute)
+
+	jti := entityPkg.NewID().String()
+	revoked, err := cache.IsTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Revogações feitas através do cache ficam visíveis imediatamente
+	assert.NoError(t, cache.RevokeToken(entity.NewRevokedToken(jti, entityPkg.NewID(), time.Now().Add(time.Hour))))
+	revoked, err = cache.IsTokenRevoked(jti)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	userID := entityPkg.NewID().String()
+	cutoff := time.Now().Truncate(time.Second)
+	assert.NoError(t, cache.RevokeUserTokens(userID, cutoff))
+	before, err := cache.FindUserTokensRevokedBefore(userID)
+	assert.NoError(t, err)
+	assert.True(t, cutoff.Equal(before))
+}
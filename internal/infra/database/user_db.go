@@ -1,6 +1,9 @@
 package database
 
 import (
+	"strings"
+	"time"
+
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"gorm.io/gorm"
 )
@@ -21,7 +24,7 @@ func (u *UserDb) CreateUser(user *entity.User) error {
 
 func (u *UserDb) FindUserByEmail(email string) (*entity.User, error) {
 	var user entity.User
-	err := u.DB.Where("email = ? ", email).First(&user).Error
+	err := u.DB.Preload("Role").Where("email = ? ", email).First(&user).Error
 
 	if err != nil {
 		return nil, err
@@ -32,10 +35,131 @@ func (u *UserDb) FindUserByEmail(email string) (*entity.User, error) {
 
 func (u *UserDb) FindUserById(id string) (*entity.User, error) {
 	var user entity.User
-	err := u.DB.First(&user, "id=?", id).Error
+	err := u.DB.Preload("Role").First(&user, "id=?", id).Error
 	return &user, err
 }
 
+// FindUsers returns a page of users with their roles and the total of users
+// matching the filters.
+func (u *UserDb) FindUsers(query UserQuery) ([]entity.User, int64, error) {
+	db := u.DB.Model(&entity.User{})
+
+	if query.Search != "" {
+		search := "%" + escapeLike(strings.ToLower(query.Search)) + "%"
+		db = db.Where("LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!'", search, search)
+	}
+
+	if query.RoleID != "" {
+		db = db.Where("role_id = ?", query.RoleID)
+	}
+
+	if query.Disabled != nil {
+		db = db.Where("disabled = ?", *query.Disabled)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []entity.User
+	err := db.Preload("Role").
+		Order("name").
+		Order("id").
+		Limit(query.Limit).
+		Offset((query.Page - 1) * query.Limit).
+		Find(&users).
+		Error
+	return users, total, err
+}
+
 func (u *UserDb) UpdateUser(user *entity.User) error {
 	return u.DB.Save(user).Error
 }
+
+// UpdateUserColumns writes only the given columns of the user, so changes
+// made meanwhile to the others, like a login lock, aren't undone.
+func (u *UserDb) UpdateUserColumns(user *entity.User, columns ...string) error {
+	return u.DB.Model(user).Select(columns).Updates(user).Error
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code, unless a code
+// of the same or a later step was accepted already. It returns false then,
+// so concurrent requests can't both use one code.
+func (u *UserDb) UseTOTPStep(id string, step int64) (bool, error) {
+	result := u.DB.Model(&entity.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordFailedLogin counts a wrong password of the user and locks the login
+// as the lockout says. It returns until when the login is locked, nil if it
+// isn't. The count is incremented in the database, so concurrent attempts
+// all add up.
+func (u *UserDb) RecordFailedLogin(id string, lockout entity.LoginLockout) (*time.Time, error) {
+	var until *time.Time
+	err := u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("id = ?", id).Update("failed_logins", gorm.Expr("failed_logins + 1")).Error; err != nil {
+			return err
+		}
+
+		var failures int
+		if err := tx.Model(&entity.User{}).Where("id = ?", id).Select("failed_logins").Scan(&failures).Error; err != nil {
+			return err
+		}
+
+		until = lockout.Until(failures, time.Now())
+		return tx.Model(&entity.User{}).Where("id = ?", id).Update("locked_until", until).Error
+	})
+	return until, err
+}
+
+// ResetFailedLogins forgets the wrong passwords of the user after a
+// successful login.
+func (u *UserDb) ResetFailedLogins(id string) error {
+	return u.DB.Model(&entity.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil}).
+		Error
+}
+
+// DeleteUser removes the user together with their cart, refresh tokens, API
+// keys, MFA recovery codes and password reset tokens. Orders are kept, since
+// they are part of the store history, and login attempts stay until they
+// expire.
+func (u *UserDb) DeleteUser(id string) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&entity.User{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		carts := tx.Model(&entity.Cart{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Where("cart_id IN (?)", carts).Delete(&entity.CartItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&entity.Cart{}).Error; err != nil {
+			return err
+		}
+
+		// As tabelas não têm chaves estrangeiras, então as credenciais do
+		// usuário precisam ser apagadas aqui
+		keys := tx.Model(&entity.APIKey{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Table("api_key_scopes").Where("api_key_id IN (?)", keys).Delete(nil).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&entity.APIKey{}, &entity.MFARecoveryCode{}, &entity.PasswordResetToken{}, &entity.RefreshToken{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -2,22 +2,16 @@ package database
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupUserDB(t *testing.T) (*gorm.DB, *UserDb, *entity.Role) {
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("could not open db: %v", err)
-	}
-	err = db.AutoMigrate(&entity.Role{}, &entity.User{})
-	if err != nil {
-		t.Fatalf("auto migrate failed: %v", err)
-	}
+	db := newTestDB(t, &entity.Role{}, &entity.User{})
 	role, _ := entity.NewRole("tester")
 	db.Create(role)
 	return db, NewUserDb(db), role
@@ -88,3 +82,182 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, "Lucena", productFound.Name)
 	assert.Equal(t, role2.ID, productFound.RoleID)
 }
+
+func TestUpdateUserColumns(t *testing.T) {
+	_, userDB, role := setupUserDB(t)
+	user, _ := entity.NewUser("Mateus", "m@gmail.com", "123456789", role.ID)
+	assert.NoError(t, userDB.CreateUser(user))
+
+	// Um bloqueio gravado enquanto o admin editava não pode ser desfeito
+	stale, _ := userDB.FindUserById(user.ID.String())
+	_, err := userDB.RecordFailedLogin(user.ID.String(), entity.LoginLockout{Threshold: 1, Duration: time.Hour})
+	assert.NoError(t, err)
+
+	stale.Disabled = true
+	stale.Name = "Outro"
+	assert.NoError(t, userDB.UpdateUserColumns(stale, "disabled"))
+
+	found, _ := userDB.FindUserById(user.ID.String())
+	assert.True(t, found.Disabled)
+	assert.Equal(t, "Mateus", found.Name)
+	assert.Equal(t, 1, found.FailedLogins)
+	assert.NotNil(t, found.LockedUntil)
+
+	found.Disabled = false
+	assert.NoError(t, userDB.UpdateUserColumns(found, "disabled"))
+	found, _ = userDB.FindUserById(user.ID.String())
+	assert.False(t, found.Disabled)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	_, userDB, role := setupUserDB(t)
+	user, _ := entity.NewUser("Mateus", "m@gmail.com", "123456789", role.ID)
+	assert.NoError(t, userDB.CreateUser(user))
+
+	used, err := userDB.UseTOTPStep(user.ID.String(), 100)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	// O mesmo passo, ou um anterior, não é aceito de novo
+	for _, step := range []int64{100, 99} {
+		used, err = userDB.UseTOTPStep(user.ID.String(), step)
+		assert.NoError(t, err)
+		assert.False(t, used)
+	}
+
+	used, err = userDB.UseTOTPStep(user.ID.String(), 101)
+	assert.NoError(t, err)
+	assert.True(t, used)
+}
+
+func TestRecordFailedLogin(t *testing.T) {
+	_, userDB, role := setupUserDB(t)
+	user, _ := entity.NewUser("Mateus", "m@gmail.com", "123456789", role.ID)
+	assert.NoError(t, userDB.CreateUser(user))
+	lockout := entity.LoginLockout{Threshold: 5, Duration: time.Hour}
+
+	for i := 0; i < 3; i++ {
+		until, err := userDB.RecordFailedLogin(user.ID.String(), lockout)
+		assert.NoError(t, err)
+		assert.Nil(t, until)
+	}
+
+	until, err := userDB.RecordFailedLogin(user.ID.String(), lockout)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Second), *until, time.Second)
+
+	until, err = userDB.RecordFailedLogin(user.ID.String(), lockout)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *until, time.Second)
+
+	found, _ := userDB.FindUserById(user.ID.String())
+	assert.Equal(t, 5, found.FailedLogins)
+	assert.True(t, found.LoginLockedFor(time.Now()) > 0)
+
+	assert.NoError(t, userDB.ResetFailedLogins(user.ID.String()))
+	found, _ = userDB.FindUserById(user.ID.String())
+	assert.Zero(t, found.FailedLogins)
+	assert.Nil(t, found.LockedUntil)
+}
+
+func TestFindUsers(t *testing.T) {
+	db, userDB, customer := setupUserDB(t)
+	admin, _ := entity.NewRole("admin")
+	db.Create(admin)
+
+	ana, _ := entity.NewUser("Ana", "ana@shop.com", "123456789", admin.ID)
+	bruno, _ := entity.NewUser("Bruno", "bruno@mail.com", "123456789", customer.ID)
+	carla, _ := entity.NewUser("Carla", "carla_100%@shop.com", "123456789", customer.ID)
+	carla.Disabled = true
+	for _, user := range []*entity.User{carla, bruno, ana} {
+		assert.NoError(t, userDB.CreateUser(user))
+	}
+
+	emails := func(users []entity.User) []string {
+		result := make([]string, 0, len(users))
+		for _, user := range users {
+			result = append(result, user.Email)
+		}
+		return result
+	}
+
+	users, total, err := userDB.FindUsers(UserQuery{Page: 1, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []string{"ana@shop.com", "bruno@mail.com"}, emails(users))
+	assert.Equal(t, "admin", users[0].Role.Name)
+
+	users, total, err = userDB.FindUsers(UserQuery{Page: 2, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []string{"carla_100%@shop.com"}, emails(users))
+
+	users, _, err = userDB.FindUsers(UserQuery{Page: 1, Limit: 10, Search: "SHOP"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ana@shop.com", "carla_100%@shop.com"}, emails(users))
+
+	users, _, err = userDB.FindUsers(UserQuery{Page: 1, Limit: 10, Search: "0%"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carla_100%@shop.com"}, emails(users))
+
+	disabled := false
+	users, _, err = userDB.FindUsers(UserQuery{Page: 1, Limit: 10, RoleID: customer.ID.String(), Disabled: &disabled})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bruno@mail.com"}, emails(users))
+}
+
+func TestDeleteUser(t *testing.T) {
+	db, userDB, role := setupUserDB(t)
+	assert.NoError(t, db.AutoMigrate(&entity.Cart{}, &entity.CartItem{}, &entity.RefreshToken{},
+		&entity.Permission{}, &entity.APIKey{}, &entity.MFARecoveryCode{}, &entity.PasswordResetToken{}))
+
+	user, _ := entity.NewUser("Mateus", "m@gmail.com", "123456789", role.ID)
+	other, _ := entity.NewUser("Lucena", "l@gmail.com", "123456789", role.ID)
+	assert.NoError(t, userDB.CreateUser(user))
+	assert.NoError(t, userDB.CreateUser(other))
+
+	cart := entity.NewCart(user.ID)
+	assert.NoError(t, db.Create(cart).Error)
+	item := entity.CartItem{ID: entityPkg.NewID(), CartID: cart.ID, ProductID: entityPkg.NewID(), Quantity: 1}
+	assert.NoError(t, db.Create(&item).Error)
+	otherCart := entity.NewCart(other.ID)
+	assert.NoError(t, db.Create(otherCart).Error)
+
+	token, _, err := entity.NewRefreshToken(user.ID, user.ID, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(token).Error)
+
+	permission, _ := entity.NewPermission(entity.PermissionProductWrite, "")
+	assert.NoError(t, db.Create(permission).Error)
+	key, _, err := entity.NewAPIKey(user.ID, "CI", []entity.Permission{*permission}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(key).Error)
+	codes, _, err := entity.NewMFARecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&codes).Error)
+	resetToken, _, err := entity.NewPasswordResetToken(user.ID, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(resetToken).Error)
+
+	assert.NoError(t, userDB.DeleteUser(user.ID.String()))
+
+	_, err = userDB.FindUserById(user.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var count int64
+	db.Model(&entity.Cart{}).Where("user_id = ?", user.ID).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&entity.CartItem{}).Where("cart_id = ?", cart.ID).Count(&count)
+	assert.Zero(t, count)
+	for _, model := range []interface{}{&entity.RefreshToken{}, &entity.APIKey{}, &entity.MFARecoveryCode{}, &entity.PasswordResetToken{}} {
+		db.Model(model).Where("user_id = ?", user.ID).Count(&count)
+		assert.Zero(t, count)
+	}
+	db.Table("api_key_scopes").Where("api_key_id = ?", key.ID).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&entity.Cart{}).Where("user_id = ?", other.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	err = userDB.DeleteUser(user.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
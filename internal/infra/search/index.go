@@ -0,0 +1,329 @@
+// Package search implements an in-memory inverted index used for the full
+// text product search. It needs no external service, so it works the same
+// with every database driver and in tests.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// prefixWeight is the share of the score a term gets when it only
+	// matches the beginning of a word.
+	prefixWeight = 0.5
+
+	snippetLength = 160
+	markOpen      = "<mark>"
+	markClose     = "</mark>"
+)
+
+// Field is a piece of text of a document. Matches in fields with a higher
+// weight rank the document higher.
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Hit is a document matching a query. Highlights has, for each matched
+// field, an HTML-escaped snippet with the matches wrapped in <mark> tags.
+type Hit struct {
+	ID         string            `json:"id"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string][]Field
+	postings map[string]map[string]float64
+	// terms is kept sorted for the prefix lookups.
+	terms []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string][]Field),
+		postings: make(map[string]map[string]float64),
+	}
+}
+
+// Add indexes the document, replacing a previous version with the same ID.
+func (i *Index) Add(id string, fields ...Field) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+	i.docs[id] = fields
+
+	for _, field := range fields {
+		weight := field.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for _, token := range tokenize(field.Text) {
+			docs, ok := i.postings[token.term]
+			if !ok {
+				docs = make(map[string]float64)
+				i.postings[token.term] = docs
+				i.insertTerm(token.term)
+			}
+			docs[id] += weight
+		}
+	}
+}
+
+func (i *Index) Remove(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.docs)
+}
+
+func (i *Index) remove(id string) {
+	fields, ok := i.docs[id]
+	if !ok {
+		return
+	}
+	delete(i.docs, id)
+
+	for _, field := range fields {
+		for _, token := range tokenize(field.Text) {
+			docs, ok := i.postings[token.term]
+			if !ok {
+				continue
+			}
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(i.postings, token.term)
+				i.deleteTerm(token.term)
+			}
+		}
+	}
+}
+
+func (i *Index) insertTerm(term string) {
+	pos := sort.SearchStrings(i.terms, term)
+	i.terms = append(i.terms, "")
+	copy(i.terms[pos+1:], i.terms[pos:])
+	i.terms[pos] = term
+}
+
+func (i *Index) deleteTerm(term string) {
+	pos := sort.SearchStrings(i.terms, term)
+	if pos < len(i.terms) && i.terms[pos] == term {
+		i.terms = append(i.terms[:pos], i.terms[pos+1:]...)
+	}
+}
+
+// expand returns the indexed terms starting with the query term, with the
+// weight of the match: 1 for the term itself and prefixWeight for longer
+// terms.
+func (i *Index) expand(term string) map[string]float64 {
+	matches := make(map[string]float64)
+	for pos := sort.SearchStrings(i.terms, term); pos < len(i.terms); pos++ {
+		candidate := i.terms[pos]
+		if !strings.HasPrefix(candidate, term) {
+			break
+		}
+		if candidate == term {
+			matches[candidate] = 1
+		} else {
+			matches[candidate] = prefixWeight
+		}
+	}
+	return matches
+}
+
+// Search returns up to limit documents containing every word of the query,
+// either whole or as a prefix, ranked by TF-IDF. Matching ignores case and
+// accents.
+func (i *Index) Search(query string, limit int) []Hit {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	tokens := tokenize(query)
+	if len(tokens) == 0 || limit <= 0 {
+		return []Hit{}
+	}
+
+	total := float64(len(i.docs))
+	scores := make(map[string]float64)
+	matched := make(map[string]map[string]bool)
+
+	for n, token := range tokens {
+		tokenScores := make(map[string]float64)
+		for term, matchWeight := range i.expand(token.term) {
+			docs := i.postings[term]
+			idf := math.Log(1 + total/float64(len(docs)))
+			for id, tf := range docs {
+				score := tf * idf * matchWeight
+				if score > tokenScores[id] {
+					tokenScores[id] = score
+				}
+				if matched[id] == nil {
+					matched[id] = make(map[string]bool)
+				}
+				matched[id][term] = true
+			}
+		}
+
+		// Todas as palavras da busca precisam aparecer no documento
+		if n == 0 {
+			scores = tokenScores
+			continue
+		}
+		for id := range scores {
+			if score, ok := tokenScores[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for n := range hits {
+		hits[n].Highlights = highlight(i.docs[hits[n].ID], matched[hits[n].ID])
+	}
+	return hits
+}
+
+func highlight(fields []Field, terms map[string]bool) map[string]string {
+	highlights := make(map[string]string)
+	for _, field := range fields {
+		if snippet, ok := snippet(field.Text, terms); ok {
+			highlights[field.Name] = snippet
+		}
+	}
+	return highlights
+}
+
+// snippet marks the matched words of the text, cutting it around the first
+// match when it is too long.
+func snippet(text string, terms map[string]bool) (string, bool) {
+	var matches []token
+	for _, t := range tokenize(text) {
+		if terms[t.term] {
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start, end := 0, len(text)
+	if len(text) > snippetLength {
+		start = matches[0].start - snippetLength/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + snippetLength
+		if end > len(text) {
+			end = len(text)
+		}
+		start, end = wordBoundary(text, start, false), wordBoundary(text, end, true)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString(markClose)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// wordBoundary moves pos to the closest space, backwards or forwards, so the
+// snippet doesn't cut words in half.
+func wordBoundary(text string, pos int, forward bool) int {
+	if forward {
+		if next := strings.IndexByte(text[pos:], ' '); next >= 0 {
+			return pos + next
+		}
+		return len(text)
+	}
+	if prev := strings.LastIndexByte(text[:pos], ' '); prev >= 0 {
+		return prev + 1
+	}
+	return 0
+}
+
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits the text into words, keeping their byte offsets in the
+// original text, and normalizes them to lower case without accents.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for pos, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = pos
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{normalize(text[start:pos]), start, pos})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{normalize(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+func normalize(word string) string {
+	folder := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(folder, word)
+	if err != nil {
+		folded = word
+	}
+	return strings.ToLower(folded)
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndex() *Index {
+	index := NewIndex()
+	index.Add("1", Field{Name: "name", Text: "Café Expresso Italiano", Weight: 1})
+	index.Add("2", Field{Name: "name", Text: "Cafeteira Elétrica", Weight: 1})
+	index.Add("3", Field{Name: "name", Text: "Caneca de café", Weight: 1})
+	index.Add("4", Field{Name: "name", Text: "Chaleira Elétrica", Weight: 1})
+	return index
+}
+
+func ids(hits []Hit) []string {
+	result := make([]string, len(hits))
+	for i, hit := range hits {
+		result[i] = hit.ID
+	}
+	return result
+}
+
+func TestSearchIgnoresCaseAndAccents(t *testing.T) {
+	index := newTestIndex()
+
+	hits := index.Search("ELETRICA", 10)
+	assert.ElementsMatch(t, []string{"2", "4"}, ids(hits))
+
+	hits = index.Search("cafe", 10)
+	// Palavra inteira pesa mais do que prefixo
+	assert.Equal(t, []string{"1", "3", "2"}, ids(hits))
+}
+
+func TestSearchRequiresEveryWord(t *testing.T) {
+	index := newTestIndex()
+
+	hits := index.Search("caf ele", 10)
+	assert.Equal(t, []string{"2"}, ids(hits))
+
+	assert.Empty(t, index.Search("cafe chaleira", 10))
+	assert.Empty(t, index.Search("  ", 10))
+}
+
+func TestSearchHighlights(t *testing.T) {
+	index := newTestIndex()
+
+	hits := index.Search("cafe", 1)
+	assert.Equal(t, "<mark>Café</mark> Expresso Italiano", hits[0].Highlights["name"])
+
+	long := strings.Repeat("palavra ", 40) + "Ventilador <b>Turbo</b> " + strings.Repeat("outra ", 40)
+	index.Add("5", Field{Name: "description", Text: long})
+	hits = index.Search("turbo", 1)
+	snippet := hits[0].Highlights["description"]
+	assert.Contains(t, snippet, "&lt;b&gt;<mark>Turbo</mark>&lt;/b&gt;")
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+}
+
+func TestAddReplacesAndRemoveDeletes(t *testing.T) {
+	index := newTestIndex()
+
+	index.Add("1", Field{Name: "name", Text: "Chá Verde"})
+	assert.Equal(t, []string{"3", "2"}, ids(index.Search("cafe", 10)))
+	assert.Equal(t, []string{"1", "4"}, ids(index.Search("cha", 10)))
+
+	index.Remove("4")
+	assert.Empty(t, index.Search("chaleira", 10))
+	assert.Equal(t, 3, index.Len())
+}
@@ -0,0 +1,147 @@
+// Package auth holds who a request is made by. The Principal is read once
+// from the claims of the verified token and kept in the request context,
+// so handlers and middlewares don't dig into the claims themselves.
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+// Claims of the access tokens besides the registered ones.
+const (
+	ClaimRole        = "role"
+	ClaimRoleID      = "role_id"
+	ClaimPermissions = "permissions"
+	ClaimMFA         = "mfa"
+	ClaimAPIKey      = "api_key"
+	ClaimScopes      = "scopes"
+)
+
+var ErrInvalidClaims = errors.New("Invalid token claims")
+
+// Principal is the user a request is made by, as the token says.
+type Principal struct {
+	UserID entityPkg.ID
+	// Role is the name of the role and RoleID its ID.
+	Role   string
+	RoleID string
+	// Permissions are those of the role when the token was issued.
+	Permissions []string
+	// TokenID is the jti of the token, or the ID of the API key.
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// MFA tells the token was issued after the second factor.
+	MFA bool
+
+	// APIKeyID is set when the request was made with an API key, and Scopes
+	// are the permissions the key is limited to, if any.
+	APIKeyID string
+	Scopes   []string
+}
+
+// Claims returns the claims of a token for the principal, to be signed.
+func (p *Principal) Claims() map[string]interface{} {
+	claims := map[string]interface{}{
+		jwt.SubjectKey:   p.UserID.String(),
+		jwt.JwtIDKey:     p.TokenID,
+		jwt.IssuedAtKey:  p.IssuedAt.Unix(),
+		ClaimRole:        p.Role,
+		ClaimRoleID:      p.RoleID,
+		ClaimPermissions: p.Permissions,
+		ClaimMFA:         p.MFA,
+	}
+	if !p.ExpiresAt.IsZero() {
+		claims[jwt.ExpirationKey] = p.ExpiresAt.Unix()
+	}
+	if p.APIKeyID != "" {
+		claims[ClaimAPIKey] = p.APIKeyID
+	}
+	if len(p.Scopes) > 0 {
+		claims[ClaimScopes] = p.Scopes
+	}
+	return claims
+}
+
+// FromToken reads the principal from the claims of a verified token.
+func FromToken(token jwt.Token) (*Principal, error) {
+	userID, err := entityPkg.ParseID(token.Subject())
+	if err != nil || token.JwtID() == "" {
+		return nil, ErrInvalidClaims
+	}
+
+	claims := token.PrivateClaims()
+	p := &Principal{
+		UserID:    userID,
+		TokenID:   token.JwtID(),
+		IssuedAt:  token.IssuedAt(),
+		ExpiresAt: token.Expiration(),
+	}
+	p.Role, _ = claims[ClaimRole].(string)
+	p.RoleID, _ = claims[ClaimRoleID].(string)
+	p.MFA, _ = claims[ClaimMFA].(bool)
+	p.APIKeyID, _ = claims[ClaimAPIKey].(string)
+
+	if p.Permissions, err = stringList(claims[ClaimPermissions]); err != nil {
+		return nil, err
+	}
+	if p.Scopes, err = stringList(claims[ClaimScopes]); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// stringList reads a list claim, which is []interface{} once decoded from
+// JSON.
+func stringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, ErrInvalidClaims
+			}
+			list[i] = s
+		}
+		return list, nil
+	default:
+		return nil, ErrInvalidClaims
+	}
+}
+
+// Can reports whether the principal has the permission. API keys with
+// scopes also need it among them.
+func (p *Principal) Can(permission string) bool {
+	if !slices.Contains(p.Permissions, permission) {
+		return false
+	}
+	return len(p.Scopes) == 0 || slices.Contains(p.Scopes, permission)
+}
+
+// IsAPIKey reports whether the request was made with an API key.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != ""
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal of an authenticated request.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTrip turns the claims into a token the way a signed one comes back
+// from the verifier, with the lists decoded from JSON.
+func roundTrip(t *testing.T, claims map[string]interface{}) jwt.Token {
+	token := jwt.New()
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			t.Fatalf("could not set claim %s: %v", name, err)
+		}
+	}
+	payload, err := json.Marshal(token)
+	if err != nil {
+		t.Fatalf("could not marshal token: %v", err)
+	}
+	parsed, err := jwt.Parse(payload)
+	if err != nil {
+		t.Fatalf("could not parse token: %v", err)
+	}
+	return parsed
+}
+
+func TestPrincipalClaimsRoundTrip(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	principal := Principal{
+		UserID:      entityPkg.NewID(),
+		Role:        "admin",
+		RoleID:      entityPkg.NewID().String(),
+		Permissions: []string{"products:read", "products:write"},
+		TokenID:     entityPkg.NewID().String(),
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(time.Hour),
+		MFA:         true,
+	}
+
+	parsed, err := FromToken(roundTrip(t, principal.Claims()))
+	assert.Nil(t, err)
+	assert.Equal(t, principal.UserID, parsed.UserID)
+	assert.Equal(t, "admin", parsed.Role)
+	assert.Equal(t, principal.RoleID, parsed.RoleID)
+	assert.Equal(t, principal.Permissions, parsed.Permissions)
+	assert.Equal(t, principal.TokenID, parsed.TokenID)
+	assert.True(t, parsed.IssuedAt.Equal(now))
+	assert.True(t, parsed.ExpiresAt.Equal(now.Add(time.Hour)))
+	assert.True(t, parsed.MFA)
+	assert.False(t, parsed.IsAPIKey())
+	assert.Empty(t, parsed.Scopes)
+}
+
+func TestPrincipalAPIKeyScopes(t *testing.T) {
+	keyID := entityPkg.NewID().String()
+	principal := Principal{
+		UserID:      entityPkg.NewID(),
+		Role:        "manager",
+		RoleID:      entityPkg.NewID().String(),
+		Permissions: []string{"products:read", "products:write"},
+		TokenID:     keyID,
+		IssuedAt:    time.Now(),
+		APIKeyID:    keyID,
+		Scopes:      []string{"products:read"},
+	}
+
+	parsed, err := FromToken(roundTrip(t, principal.Claims()))
+	assert.Nil(t, err)
+	assert.True(t, parsed.IsAPIKey())
+	assert.True(t, parsed.ExpiresAt.IsZero())
+	assert.True(t, parsed.Can("products:read"))
+	assert.False(t, parsed.Can("products:write"))
+	assert.False(t, parsed.Can("users:read"))
+}
+
+func TestPrincipalCanWithoutScopes(t *testing.T) {
+	principal := Principal{Permissions: []string{"orders:read"}}
+
+	assert.True(t, principal.Can("orders:read"))
+	assert.False(t, principal.Can("orders:write"))
+}
+
+func TestFromTokenInvalidClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"missing subject", map[string]interface{}{jwt.JwtIDKey: "jti"}},
+		{"subject not an ID", map[string]interface{}{jwt.SubjectKey: "admin", jwt.JwtIDKey: "jti"}},
+		{"missing token ID", map[string]interface{}{jwt.SubjectKey: entityPkg.NewID().String()}},
+		{"permissions not a list", map[string]interface{}{
+			jwt.SubjectKey:   entityPkg.NewID().String(),
+			jwt.JwtIDKey:     "jti",
+			ClaimPermissions: "products:read",
+		}},
+		{"scopes with other types", map[string]interface{}{
+			jwt.SubjectKey: entityPkg.NewID().String(),
+			jwt.JwtIDKey:   "jti",
+			ClaimScopes:    []interface{}{"products:read", 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromToken(roundTrip(t, tt.claims))
+			assert.ErrorIs(t, err, ErrInvalidClaims)
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	principal := &Principal{UserID: entityPkg.NewID()}
+	found, ok := FromContext(NewContext(context.Background(), principal))
+	assert.True(t, ok)
+	assert.Same(t, principal, found)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	APIKeyDB     database.APIKeyInterface
+	PermissionDB database.PermissionInterface
+}
+
+func NewAPIKeyHandler(apiKeyDB database.APIKeyInterface, permissionDB database.PermissionInterface) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyDB:     apiKeyDB,
+		PermissionDB: permissionDB,
+	}
+}
+
+var errScopeNotFound = problem.Validation("permission not found", problem.FieldError{Field: "scopes", Message: "has permissions that don't exist"})
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of the authenticated user that weren't revoked, newest first
+// @Tags api keys
+// @Produce json
+// @Success 200 {array} entity.APIKey
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/api-keys [get]
+// @Security ApiKeyAuth
+func (ah *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID := actorFromClaims(r)
+	if userID == nil {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return
+	}
+
+	keys, err := ah.APIKeyDB.FindUserAPIKeys(userID.String())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key to call the API as the authenticated user with "Authorization: ApiKey <key>". The key is returned only once. Scopes limit it to some of the permissions of the user's role.
+// @Tags api keys
+// @Accept json
+// @Produce json
+// @Param key body dto.CreateAPIKeyInput true "API key data"
+// @Success 201 {object} dto.CreateAPIKeyOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/api-keys [post]
+// @Security ApiKeyAuth
+func (ah *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.CreateAPIKeyInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	userID := actorFromClaims(r)
+	if userID == nil {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return
+	}
+
+	scopes, err := ah.findScopes(r, input.Scopes)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	key, plain, err := entity.NewAPIKey(*userID, input.Name, scopes, input.ExpiresAt)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := ah.APIKeyDB.CreateAPIKey(key); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateAPIKeyOutput{APIKey: *key, Key: plain})
+}
+
+// UpdateAPIKey godoc
+// @Summary Rename an API key
+// @Description Change the name of an API key of the authenticated user
+// @Tags api keys
+// @Accept json
+// @Produce json
+// @Param id path string true "API key ID"
+// @Param key body dto.UpdateAPIKeyInput true "API key data"
+// @Success 200 {object} entity.APIKey
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/api-keys/{id} [put]
+// @Security ApiKeyAuth
+func (ah *APIKeyHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.UpdateAPIKeyInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	userID := actorFromClaims(r)
+	if userID == nil {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return
+	}
+
+	key, err := ah.APIKeyDB.FindUserAPIKey(userID.String(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("API key not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	if err := key.Rename(input.Name); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := ah.APIKeyDB.RenameAPIKey(key); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(key)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key of the authenticated user. It stops working right away.
+// @Tags api keys
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/api-keys/{id} [delete]
+// @Security ApiKeyAuth
+func (ah *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := actorFromClaims(r)
+	if userID == nil {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return
+	}
+
+	revoked, err := ah.APIKeyDB.RevokeAPIKey(userID.String(), chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	if !revoked {
+		problem.Write(w, r, problem.NotFound("API key not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "API key revoked successfully"})
+}
+
+// findScopes loads the permissions a new key is limited to. A key can't get
+// a permission the role of its user doesn't have.
+func (ah *APIKeyHandler) findScopes(r *http.Request, names []string) ([]entity.Permission, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	scopes, err := findPermissions(ah.PermissionDB, names)
+	if err != nil {
+		if err == errPermissionNotFound {
+			return nil, errScopeNotFound
+		}
+		return nil, problem.Internal(err)
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return nil, problem.Forbidden("invalid token")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(principal.Permissions, scope.Name) {
+			return nil, entity.ErrAPIKeyScopeNotGranted
+		}
+	}
+	return scopes, nil
+}
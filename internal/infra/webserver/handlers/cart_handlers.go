@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type CartHandler struct {
+	CartDB    database.CartInterface
+	ProductDB database.ProductInterface
+}
+
+func NewCartHandler(cartDB database.CartInterface, productDB database.ProductInterface) *CartHandler {
+	return &CartHandler{
+		CartDB:    cartDB,
+		ProductDB: productDB,
+	}
+}
+
+// GetCart godoc
+// @Summary Get the cart
+// @Description Get the authenticated user's cart. Items whose product was removed or repriced are flagged.
+// @Tags cart
+// @Produce json
+// @Success 200 {object} entity.CartSummary
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cart [get]
+// @Security ApiKeyAuth
+func (ch *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	cart, ok := ch.loadCart(w, r)
+	if !ok {
+		return
+	}
+	ch.writeSummary(w, r, cart, http.StatusOK)
+}
+
+// AddCartItem godoc
+// @Summary Add an item to the cart
+// @Description Add a product to the cart with its current price. Adding a product already in the cart increases its quantity.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param item body dto.AddCartItemInput true "Cart item"
+// @Success 200 {object} entity.CartSummary
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cart/items [post]
+// @Security ApiKeyAuth
+func (ch *CartHandler) AddCartItem(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.AddCartItemInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	cart, ok := ch.loadCart(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := ch.ProductDB.FindProductByID(input.ProductID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("product not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	item, err := entity.NewCartItem(cart.ID, product, input.Quantity)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := ch.CartDB.AddCartItem(item); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	ch.reloadAndWrite(w, r)
+}
+
+// UpdateCartItem godoc
+// @Summary Change an item quantity
+// @Description Set the quantity of a product in the cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param productId path string true "Product ID"
+// @Param item body dto.UpdateCartItemInput true "New quantity"
+// @Success 200 {object} entity.CartSummary
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cart/items/{productId} [put]
+// @Security ApiKeyAuth
+func (ch *CartHandler) UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	productID := chi.URLParam(r, "productId")
+
+	var input dto.UpdateCartItemInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	cart, ok := ch.loadCart(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.CartDB.UpdateCartItemQuantity(cart.ID.String(), productID, input.Quantity)
+	if err != nil {
+		if errors.Is(err, entity.ErrCartItemNotFound) {
+			problem.Write(w, r, problem.NotFound("cart item not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	ch.reloadAndWrite(w, r)
+}
+
+// RemoveCartItem godoc
+// @Summary Remove an item from the cart
+// @Description Remove a product from the cart
+// @Tags cart
+// @Produce json
+// @Param productId path string true "Product ID"
+// @Success 200 {object} entity.CartSummary
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /cart/items/{productId} [delete]
+// @Security ApiKeyAuth
+func (ch *CartHandler) RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	productID := chi.URLParam(r, "productId")
+
+	cart, ok := ch.loadCart(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.CartDB.RemoveCartItem(cart.ID.String(), productID)
+	if err != nil {
+		if errors.Is(err, entity.ErrCartItemNotFound) {
+			problem.Write(w, r, problem.NotFound("cart item not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	ch.reloadAndWrite(w, r)
+}
+
+// ClearCart godoc
+// @Summary Clear the cart
+// @Description Remove every item from the cart
+// @Tags cart
+// @Produce json
+// @Success 200 {object} entity.CartSummary
+// @Failure 500 {object} problem.Problem
+// @Router /cart [delete]
+// @Security ApiKeyAuth
+func (ch *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
+	cart, ok := ch.loadCart(w, r)
+	if !ok {
+		return
+	}
+
+	if err := ch.CartDB.ClearCart(cart.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	ch.reloadAndWrite(w, r)
+}
+
+// loadCart finds the cart of the user in the token, writing the error
+// response when it can't.
+func (ch *CartHandler) loadCart(w http.ResponseWriter, r *http.Request) (*entity.Cart, bool) {
+	userID, ok := userIDFromClaims(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	cart, err := ch.CartDB.FindOrCreateCart(userID.String())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return nil, false
+	}
+
+	return cart, true
+}
+
+func (ch *CartHandler) reloadAndWrite(w http.ResponseWriter, r *http.Request) {
+	cart, ok := ch.loadCart(w, r)
+	if !ok {
+		return
+	}
+	ch.writeSummary(w, r, cart, http.StatusOK)
+}
+
+// writeSummary compares the cart with the current products and writes it.
+func (ch *CartHandler) writeSummary(w http.ResponseWriter, r *http.Request, cart *entity.Cart, status int) {
+	ids := make([]string, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		ids = append(ids, item.ProductID.String())
+	}
+
+	products, err := ch.ProductDB.FindProductsByIDs(ids)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	current := make(map[entityPkg.ID]entity.Product, len(products))
+	for _, product := range products {
+		current[product.ID] = product
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(cart.Summarize(current))
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type CategoryHandler struct {
+	CategoryDB database.CategoryInterface
+}
+
+func NewCategoryHandler(db database.CategoryInterface) *CategoryHandler {
+	return &CategoryHandler{
+		CategoryDB: db,
+	}
+}
+
+// GetCategoryTree godoc
+// @Summary List categories
+// @Description Get every category nested under its parent
+// @Tags categories
+// @Produce json
+// @Success 200 {array} entity.Category
+// @Failure 500 {object} problem.Problem
+// @Router /category [get]
+func (ch *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	categories, err := ch.CategoryDB.FindAllCategories()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	tree := entity.BuildCategoryTree(categories)
+	if tree == nil {
+		tree = []entity.Category{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tree)
+}
+
+// GetCategory godoc
+// @Summary Get a category
+// @Description Retrieve a category and its subtree by ID
+// @Tags categories
+// @Produce json
+// @Param id path string true "Category ID"
+// @Success 200 {object} entity.Category
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /category/{id} [get]
+func (ch *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	categories, err := ch.CategoryDB.FindAllCategories()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	// Monta a árvore inteira e devolve apenas o ramo pedido
+	var find func(nodes []entity.Category) *entity.Category
+	find = func(nodes []entity.Category) *entity.Category {
+		for i := range nodes {
+			if nodes[i].ID.String() == id {
+				return &nodes[i]
+			}
+			if found := find(nodes[i].Children); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	category := find(entity.BuildCategoryTree(categories))
+	if category == nil {
+		problem.Write(w, r, problem.NotFound("category not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(category)
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Description Create a category, optionally nested under a parent
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body dto.CreateCategoryInput true "Category data"
+// @Success 201 {object} entity.Category
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/category [post]
+// @Security ApiKeyAuth
+func (ch *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.CreateCategoryInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	parentID, err := ch.parseParentID(input.ParentID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	category, err := entity.NewCategory(input.Name, parentID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := ch.CategoryDB.CreateCategory(category); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Rename a category or move it under another parent
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param category body dto.UpdateCategoryInput true "Category data"
+// @Success 200 {object} entity.Category
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/category/{id} [put]
+// @Security ApiKeyAuth
+func (ch *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := chi.URLParam(r, "id")
+
+	var input dto.UpdateCategoryInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	category, err := ch.CategoryDB.FindCategoryByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("category not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	parentID, err := ch.parseParentID(input.ParentID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Impede mover a categoria para dentro da sua própria subárvore
+	if parentID != nil {
+		descendants, err := ch.CategoryDB.FindDescendantIDs(id)
+		if err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+		for _, descendant := range descendants {
+			if descendant == parentID.String() {
+				problem.Write(w, r, entity.ErrCategoryCycle)
+				return
+			}
+		}
+	}
+
+	category.Name = input.Name
+	category.ParentID = parentID
+
+	if err := category.ValidateCategory(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := ch.CategoryDB.UpdateCategory(category); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Delete a category. Its children are moved to its parent and products lose the link to it.
+// @Tags categories
+// @Produce json
+// @Param id path string true "Category ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/category/{id} [delete]
+// @Security ApiKeyAuth
+func (ch *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := ch.CategoryDB.DeleteCategory(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("category not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "category deleted successfully"})
+}
+
+// parseParentID validates that the parent, when given, exists.
+func (ch *CategoryHandler) parseParentID(raw *string) (*entityPkg.ID, error) {
+	if raw == nil || *raw == "" {
+		return nil, nil
+	}
+
+	parentID, err := entityPkg.ParseID(*raw)
+	if err != nil {
+		return nil, problem.Validation("invalid parent_id", problem.FieldError{Field: "parent_id", Message: "is not a valid ID"})
+	}
+
+	if _, err := ch.CategoryDB.FindCategoryByID(parentID.String()); err != nil {
+		return nil, problem.Validation("parent category not found", problem.FieldError{Field: "parent_id", Message: "category not found"})
+	}
+
+	return &parentID, nil
+}
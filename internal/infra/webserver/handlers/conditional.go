@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+)
+
+// productETag is the strong entity tag of the product, taken from its
+// version.
+func productETag(product *entity.Product) string {
+	return fmt.Sprintf(`"%d"`, product.Version)
+}
+
+// etagMatches reports whether the tag is listed in an If-Match or
+// If-None-Match header. "*" matches any tag. The weak comparison of
+// If-None-Match ignores the W/ prefix; the strong one of If-Match never
+// matches weak tags.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces the If-Match precondition of a write. The header is
+// optional on purpose: requests without it are let through, and the write
+// itself still checks the version the request read.
+func checkIfMatch(r *http.Request, etag string) error {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" || etagMatches(header, etag, false) {
+		return nil
+	}
+	return problem.PreconditionFailed("the resource was changed, fetch it again and retry")
+}
+
+// notModified answers 304 Not Modified when If-None-Match lists the current
+// tag, so the client can reuse its cached copy.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := strings.Join(r.Header.Values("If-None-Match"), ",")
+	if header == "" || !etagMatches(header, etag, true) {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
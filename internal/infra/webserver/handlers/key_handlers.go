@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/jwtkeys"
+)
+
+// jwksMaxAge is how long clients may cache the key set. A new signing key
+// is only used after it's published, so it has to stay below the time
+// between adding a key and signing with it.
+const jwksMaxAge = "public, max-age=3600"
+
+type KeyHandler struct {
+	Keys *jwtkeys.KeySet
+}
+
+func NewKeyHandler(keys *jwtkeys.KeySet) *KeyHandler {
+	return &KeyHandler{
+		Keys: keys,
+	}
+}
+
+// GetJWKS godoc
+// @Summary Get the token signing keys
+// @Description Get the public keys that verify access tokens, as a JSON Web Key Set. Empty when tokens are signed with a shared secret
+// @Tags auth
+// @Produce json
+// @Success 200 {object} object
+// @Failure 500 {object} problem.Problem
+// @Router /.well-known/jwks.json [get]
+func (kh *KeyHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, err := kh.Keys.JWKS()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", jwksMaxAge)
+	w.WriteHeader(http.StatusOK)
+	w.Write(jwks)
+}
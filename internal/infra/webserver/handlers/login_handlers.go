@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/ratelimit"
+	"gorm.io/gorm"
+)
+
+// An address gets many more free failures than an account, since several
+// users may share it, and is then slowed down the same way. Its failures
+// are forgotten after an hour without new ones.
+const (
+	loginIPFreeFailures = 20
+	loginIPBackoffBase  = time.Second
+	loginIPBackoffMax   = 15 * time.Minute
+	loginIPBackoffReset = time.Hour
+)
+
+// LoginProtection configures the brute-force protection of the login: the
+// lockout of accounts after wrong passwords and the log of the attempts.
+type LoginProtection struct {
+	AttemptDB database.LoginAttemptInterface
+	Lockout   entity.LoginLockout
+}
+
+func newLoginIPBackoff() *ratelimit.Backoff {
+	return ratelimit.NewBackoff(loginIPFreeFailures, loginIPBackoffBase, loginIPBackoffMax, loginIPBackoffReset)
+}
+
+// clientIP returns the address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordLogin logs a login attempt. A failure to log doesn't fail the login.
+func (uh *UserHandler) recordLogin(r *http.Request, userID *entityPkg.ID, email, outcome string) {
+	attempt := entity.NewLoginAttempt(userID, email, clientIP(r), r.UserAgent(), outcome)
+	if err := uh.Login.AttemptDB.CreateLoginAttempt(attempt); err != nil {
+		log.Printf("Erro ao registrar a tentativa de login de %s: %v\n", attempt.Email, err)
+	}
+}
+
+// failLogin counts a wrong password against the account and the address.
+func (uh *UserHandler) failLogin(r *http.Request, u *entity.User) error {
+	uh.loginIPBackoff.Fail(clientIP(r))
+	_, err := uh.UserDb.RecordFailedLogin(u.ID.String(), uh.Login.Lockout)
+	return err
+}
+
+// failUnknownLogin answers a login with an email without an account. The
+// email is locked out like an account would be, so the answers don't tell
+// which emails are registered.
+func (uh *UserHandler) failUnknownLogin(w http.ResponseWriter, r *http.Request, email string) {
+	email = entity.NormalizeEmail(email)
+	failure, err := uh.Login.AttemptDB.FindLoginFailure(email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if wait := failure.LoginLockedFor(time.Now()); wait > 0 {
+		uh.recordLogin(r, nil, email, entity.LoginLocked)
+		writeTooManyRequests(w, r, wait, "too many failed logins, try again later")
+		return
+	}
+
+	uh.loginIPBackoff.Fail(clientIP(r))
+	if _, err := uh.Login.AttemptDB.RecordLoginFailure(email, uh.Login.Lockout); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	uh.recordLogin(r, nil, email, entity.LoginFailed)
+	time.Sleep(500 * time.Millisecond) // Delay to prevent timing attacks
+	problem.Write(w, r, problem.Unauthorized("invalid credentials"))
+}
+
+// GetLoginHistory godoc
+// @Summary List recent sign-ins
+// @Description List the login attempts on the account of the authenticated user, newest first, with their address,
+// @Description user agent and outcome
+// @Tags user
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} dto.LoginAttemptListOutput
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/security/logins [get]
+// @Security ApiKeyAuth
+func (uh *UserHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID := actorFromClaims(r)
+	if userID == nil {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return
+	}
+
+	page, limit := parsePage(r, defaultPageLimit)
+	attempts, total, err := uh.Login.AttemptDB.FindUserLoginAttempts(userID.String(), page, limit)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	setPageLinks(w, r, page, limit, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.LoginAttemptListOutput{
+		Items:   attempts,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		HasNext: int64(page*limit) < total,
+	})
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user
+// @Description Lift the lock put on the login of a user after failed attempts
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.User
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id}/unlock [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadUser(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	user.Unlock()
+	uh.saveManagedUser(w, r, user, false, "failed_logins", "locked_until")
+}
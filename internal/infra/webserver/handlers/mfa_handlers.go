@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/signer"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/totp"
+)
+
+const (
+	// mfaChallengeExpiresIn is how long the second step of the login can
+	// take.
+	mfaChallengeExpiresIn = 5 * time.Minute
+	// Each user can try mfaAttemptLimit codes per mfaChallengeExpiresIn.
+	mfaAttemptLimit = 5
+)
+
+var errInvalidMFAChallenge = problem.Unauthorized("invalid or expired mfa token")
+
+// MFAConfig configures the two-factor authentication.
+type MFAConfig struct {
+	// Issuer names the account in the authenticator apps.
+	Issuer string
+	// Signer signs the challenge tokens handed out between the two steps of
+	// the login.
+	Signer *signer.Signer
+}
+
+// mfaChallenge is the signed payload of the token that proves the password
+// was right. It can't be used as an access token, and its ID is revoked once
+// it is exchanged, so it works only once.
+type mfaChallenge struct {
+	ID        string `json:"jti"`
+	UserID    string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// writeMFAChallenge answers the first step of the login of a user with
+// two-factor authentication.
+func (uh *UserHandler) writeMFAChallenge(w http.ResponseWriter, r *http.Request, u *entity.User) {
+	payload, err := json.Marshal(mfaChallenge{
+		ID:        entityPkg.NewID().String(),
+		UserID:    u.ID.String(),
+		ExpiresAt: time.Now().Add(mfaChallengeExpiresIn).Unix(),
+	})
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.MFAChallengeOutput{
+		MFARequired: true,
+		MFAToken:    uh.MFA.Signer.Sign(payload),
+		ExpiresIn:   int(mfaChallengeExpiresIn.Seconds()),
+	})
+}
+
+// VerifyMFA godoc
+// @Summary Finish a login with two-factor authentication
+// @Description Exchange the mfa_token returned by /auth/login and a TOTP or recovery code for the access and refresh
+// @Description tokens.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param challenge body dto.VerifyMFAInput true "MFA token and code"
+// @Success 200 {object} dto.GetJWTOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /auth/mfa/verify [post]
+func (uh *UserHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.VerifyMFAInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	payload, err := uh.MFA.Signer.Verify(input.MFAToken)
+	if err != nil {
+		problem.Write(w, r, errInvalidMFAChallenge)
+		return
+	}
+
+	var challenge mfaChallenge
+	if err := json.Unmarshal(payload, &challenge); err != nil || challenge.ID == "" || time.Now().Unix() > challenge.ExpiresAt {
+		problem.Write(w, r, errInvalidMFAChallenge)
+		return
+	}
+
+	used, err := uh.TokenRevocationDB.IsTokenRevoked(challenge.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	if used {
+		problem.Write(w, r, errInvalidMFAChallenge)
+		return
+	}
+
+	u, err := uh.UserDb.FindUserById(challenge.UserID)
+	if err != nil || !u.MFAEnabled {
+		problem.Write(w, r, errInvalidMFAChallenge)
+		return
+	}
+
+	// A conta pode ter sido desativada entre os dois passos
+	if err := u.CanLogin(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !uh.checkMFACode(w, r, u, input.Code, problem.Unauthorized("invalid code")) {
+		uh.recordLogin(r, &u.ID, u.Email, entity.LoginMFAFailed)
+		return
+	}
+
+	// O desafio vale uma vez só; entre requisições simultâneas, só uma o consome
+	consumed, err := uh.TokenRevocationDB.ConsumeToken(entity.NewRevokedToken(challenge.ID, u.ID, time.Unix(challenge.ExpiresAt, 0)))
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	if !consumed {
+		problem.Write(w, r, errInvalidMFAChallenge)
+		return
+	}
+
+	output, err := uh.issueTokens(u, entityPkg.NewID())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	uh.recordLogin(r, &u.ID, u.Email, entity.LoginSucceeded)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// SetupTOTP godoc
+// @Summary Start the TOTP setup
+// @Description Create a TOTP secret for the authenticated user. Add it to an authenticator app, usually by showing
+// @Description otpauth_uri as a QR code, then confirm it with a code at /user/mfa/totp/confirm. Starting again
+// @Description replaces the secret.
+// @Tags user
+// @Produce json
+// @Success 200 {object} dto.TOTPSetupOutput
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/mfa/totp/setup [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if err := user.StartMFASetup(secret); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := uh.UserDb.UpdateUser(user); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.TOTPSetupOutput{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(uh.MFA.Issuer, user.Email, secret),
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm the TOTP setup
+// @Description Turn on two-factor authentication with a code of the authenticator app. The answer has the recovery
+// @Description codes, which are shown only once. Every open session is logged out.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param code body dto.ConfirmTOTPInput true "TOTP code"
+// @Success 200 {object} dto.RecoveryCodesOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/mfa/totp/confirm [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.ConfirmTOTPInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	user, ok := uh.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if ok, retryAfter := uh.mfaAttemptRate.Allow(user.ID.String()); !ok {
+		writeTooManyRequests(w, r, retryAfter, "too many two-factor authentication attempts, try again later")
+		return
+	}
+
+	if err := user.EnableMFA(input.Code, time.Now()); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Os códigos são gravados antes, para a conta nunca ficar com MFA e sem eles
+	plain, ok := uh.replaceRecoveryCodes(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := uh.UserDb.UpdateUser(user); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	// Sessões abertas só com a senha deixam de valer
+	if err := uh.revokeUserSessions(user.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesOutput{RecoveryCodes: plain})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate the recovery codes
+// @Description Replace every recovery code of the authenticated user, used or not, with new ones. Takes a TOTP or
+// @Description recovery code.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param code body dto.ConfirmTOTPInput true "TOTP or recovery code"
+// @Success 200 {object} dto.RecoveryCodesOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/mfa/recovery-codes [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.ConfirmTOTPInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	user, ok := uh.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.MFAEnabled {
+		problem.Write(w, r, entity.ErrMFANotEnabled)
+		return
+	}
+
+	if !uh.checkMFACode(w, r, user, input.Code, entity.ErrInvalidMFACode) {
+		return
+	}
+
+	plain, ok := uh.replaceRecoveryCodes(w, r, user)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesOutput{RecoveryCodes: plain})
+}
+
+// DisableMFA godoc
+// @Summary Turn off two-factor authentication
+// @Description Turn off two-factor authentication with the password and a TOTP or recovery code. Users whose role
+// @Description requires it can't turn it off.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param credentials body dto.DisableMFAInput true "Password and code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /user/mfa/totp/disable [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.DisableMFAInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	user, ok := uh.loadCurrentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.MFAEnabled {
+		problem.Write(w, r, entity.ErrMFANotEnabled)
+		return
+	}
+
+	if user.Role.RequireMFA {
+		problem.Write(w, r, entity.ErrMFARequiredByRole)
+		return
+	}
+
+	if !user.ValidatePassword(input.Password) {
+		time.Sleep(500 * time.Millisecond) // Pequeno delay para evitar timing attacks
+		problem.Write(w, r, problem.Unauthorized("invalid password"))
+		return
+	}
+
+	if !uh.checkMFACode(w, r, user, input.Code, entity.ErrInvalidMFACode) {
+		return
+	}
+
+	user.DisableMFA()
+	if err := uh.UserDb.UpdateUser(user); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if err := uh.RecoveryCodeDB.DeleteMFARecoveryCodes(user.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "two-factor authentication disabled"})
+}
+
+// ResetUserMFA godoc
+// @Summary Reset the two-factor authentication of a user
+// @Description Turn off the two-factor authentication of a user who lost the authenticator app and the recovery
+// @Description codes, and log them out. They can set it up again after logging in with the password.
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.User
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id}/mfa [delete]
+// @Security ApiKeyAuth
+func (uh *UserHandler) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadManagedUser(w, r, "reset the two-factor authentication of")
+	if !ok {
+		return
+	}
+
+	if err := uh.RecoveryCodeDB.DeleteMFARecoveryCodes(user.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	user.DisableMFA()
+	uh.saveManagedUser(w, r, user, true, "mfa_enabled", "totp_secret", "totp_last_step")
+}
+
+// checkMFACode accepts a TOTP code or an unused recovery code of the user,
+// writing invalid when neither matches. Attempts are limited per user, so the
+// six digits can't be guessed.
+func (uh *UserHandler) checkMFACode(w http.ResponseWriter, r *http.Request, user *entity.User, code string, invalid error) bool {
+	if ok, retryAfter := uh.mfaAttemptRate.Allow(user.ID.String()); !ok {
+		writeTooManyRequests(w, r, retryAfter, "too many two-factor authentication attempts, try again later")
+		return false
+	}
+
+	if user.ValidateTOTP(code, time.Now()) {
+		// Guarda o passo do código só se ninguém o usou antes, para ele não
+		// ser aceito de novo nem por uma requisição simultânea
+		used, err := uh.UserDb.UseTOTPStep(user.ID.String(), user.TOTPLastStep)
+		if err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return false
+		}
+		if !used {
+			problem.Write(w, r, invalid)
+			return false
+		}
+		return true
+	}
+
+	used, err := uh.RecoveryCodeDB.UseMFARecoveryCode(user.ID.String(), entity.HashRecoveryCode(code))
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return false
+	}
+	if !used {
+		problem.Write(w, r, invalid)
+		return false
+	}
+	return true
+}
+
+func (uh *UserHandler) replaceRecoveryCodes(w http.ResponseWriter, r *http.Request, user *entity.User) ([]string, bool) {
+	codes, plain, err := entity.NewMFARecoveryCodes(user.ID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return nil, false
+	}
+
+	if err := uh.RecoveryCodeDB.ReplaceMFARecoveryCodes(user.ID.String(), codes); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return nil, false
+	}
+	return plain, true
+}
+
+// loadCurrentUser loads the user of the access token.
+func (uh *UserHandler) loadCurrentUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	actorID := actorFromClaims(r)
+	if actorID == nil {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return nil, false
+	}
+	return uh.loadUser(w, r, actorID.String())
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	errCartIsEmpty          = problem.Validation("cart is empty")
+	errCartHasUnavailable   = problem.Conflict("cart has items that are no longer available")
+	errOrderProductNotFound = problem.NotFound("product not found")
+)
+
+type OrderHandler struct {
+	OrderDB   database.OrderInterface
+	CartDB    database.CartInterface
+	ProductDB database.ProductInterface
+	Cursors   *cursor.Codec
+}
+
+func NewOrderHandler(orderDB database.OrderInterface, cartDB database.CartInterface, productDB database.ProductInterface, cursors *cursor.Codec) *OrderHandler {
+	return &OrderHandler{
+		OrderDB:   orderDB,
+		CartDB:    cartDB,
+		ProductDB: productDB,
+		Cursors:   cursors,
+	}
+}
+
+// CreateOrder godoc
+// @Summary Place an order
+// @Description Place an order with the given items or, when no items are sent, with the items of the user's cart, which
+// @Description then leave the cart. Items are charged at the current product price and taken out of the stock. A cart
+// @Description changed while the order is placed answers 409.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param order body dto.CreateOrderInput false "Order items"
+// @Success 201 {object} entity.Order
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /order [post]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userID, ok := userIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var input dto.CreateOrderInput
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &input); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	var cartItems []entity.CartItem
+	quantities := make(map[string]int)
+	ids := []string{}
+
+	if len(input.Items) == 0 {
+		cart, err := oh.CartDB.FindOrCreateCart(userID.String())
+		if err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+		if len(cart.Items) == 0 {
+			problem.Write(w, r, errCartIsEmpty)
+			return
+		}
+		cartItems = cart.Items
+		for _, item := range cart.Items {
+			ids = append(ids, item.ProductID.String())
+			quantities[item.ProductID.String()] += item.Quantity
+		}
+	} else {
+		for _, item := range input.Items {
+			if _, seen := quantities[item.ProductID]; !seen {
+				ids = append(ids, item.ProductID)
+			}
+			quantities[item.ProductID] += item.Quantity
+		}
+	}
+
+	products, err := oh.ProductDB.FindProductsByIDs(ids)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	byID := make(map[string]entity.Product, len(products))
+	for _, product := range products {
+		byID[product.ID.String()] = product
+	}
+
+	items := make([]entity.OrderItem, 0, len(ids))
+	for _, id := range ids {
+		product, found := byID[id]
+		if !found {
+			if len(cartItems) > 0 {
+				problem.Write(w, r, errCartHasUnavailable)
+			} else {
+				problem.Write(w, r, errOrderProductNotFound)
+			}
+			return
+		}
+
+		item, err := entity.NewOrderItem(&product, quantities[id])
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		items = append(items, *item)
+	}
+
+	order, err := entity.NewOrder(userID, items)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = oh.OrderDB.CreateOrder(order, cartItems)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = errOrderProductNotFound
+		}
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOwnOrders godoc
+// @Summary List own orders
+// @Description Get the orders of the authenticated user, newest first. The Link header points to the next page.
+// @Tags orders
+// @Produce json
+// @Param page query int false "Page number"
+// @Param after query string false "Cursor from the next Link; ignores page"
+// @Param limit query int false "Limit of orders"
+// @Param status query string false "Order status"
+// @Success 200 {array} entity.Order
+// @Failure 500 {object} problem.Problem
+// @Router /order [get]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) GetOwnOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	oh.writeOrders(w, r, database.OrderFilter{
+		UserID: userID.String(),
+		Status: r.URL.Query().Get("status"),
+	})
+}
+
+// GetOwnOrder godoc
+// @Summary Get an own order
+// @Description Get an order of the authenticated user with its history
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /order/{id} [get]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) GetOwnOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	order, ok := oh.loadOrder(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	// Pedidos de outros usuários não são revelados
+	if order.UserID != userID {
+		problem.Write(w, r, problem.NotFound("order not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// CancelOwnOrder godoc
+// @Summary Cancel an own order
+// @Description Cancel an order of the authenticated user that was not shipped yet. The items go back to the stock.
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /order/{id}/cancel [post]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) CancelOwnOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	order, ok := oh.loadOrder(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	if order.UserID != userID {
+		problem.Write(w, r, problem.NotFound("order not found"))
+		return
+	}
+
+	oh.transition(w, r, order.ID.String(), entity.OrderCancelled, &userID, "Cancelled by the customer")
+}
+
+// GetOrders godoc
+// @Summary List all orders
+// @Description Get the orders of every user, newest first. The Link header points to the next page.
+// @Tags orders
+// @Produce json
+// @Param page query int false "Page number"
+// @Param after query string false "Cursor from the next Link; ignores page"
+// @Param limit query int false "Limit of orders"
+// @Param status query string false "Order status"
+// @Param user_id query string false "Owner of the orders"
+// @Success 200 {array} entity.Order
+// @Failure 500 {object} problem.Problem
+// @Router /admin/order [get]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	oh.writeOrders(w, r, database.OrderFilter{
+		UserID: r.URL.Query().Get("user_id"),
+		Status: r.URL.Query().Get("status"),
+	})
+}
+
+// GetOrder godoc
+// @Summary Get an order
+// @Description Get any order with its history
+// @Tags orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} entity.Order
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/order/{id} [get]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := oh.loadOrder(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// TransitionOrder godoc
+// @Summary Change an order status
+// @Description Move the order to a new status. Only the moves allowed by the order state machine are accepted:
+// @Description pending -> paid|cancelled, paid -> shipped|cancelled|refunded, shipped -> delivered, delivered -> refunded.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param transition body dto.TransitionOrderInput true "New status"
+// @Success 200 {object} entity.Order
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/order/{id}/transition [post]
+// @Security ApiKeyAuth
+func (oh *OrderHandler) TransitionOrder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.TransitionOrderInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	oh.transition(w, r, chi.URLParam(r, "id"), entity.OrderStatus(input.Status), actorFromClaims(r), input.Note)
+}
+
+func (oh *OrderHandler) transition(w http.ResponseWriter, r *http.Request, id string, to entity.OrderStatus, actorID *entityPkg.ID, note string) {
+	order, err := oh.OrderDB.TransitionOrder(id, to, actorID, note)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = problem.NotFound("order not found")
+		}
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (oh *OrderHandler) loadOrder(w http.ResponseWriter, r *http.Request, id string) (*entity.Order, bool) {
+	order, err := oh.OrderDB.FindOrderByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("order not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return nil, false
+	}
+	return order, true
+}
+
+func (oh *OrderHandler) writeOrders(w http.ResponseWriter, r *http.Request, filter database.OrderFilter) {
+	page, limit := parsePage(r, 20)
+
+	after, ok := parseCursor(w, r, oh.Cursors)
+	if !ok {
+		return
+	}
+	filter.After = after
+
+	// Busca um item a mais para saber se existe próxima página
+	orders, err := oh.OrderDB.FindOrders(page, limit+1, filter)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	next := ""
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[len(orders)-1]
+		next = oh.Cursors.Encode(cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID.String(), Desc: true})
+	}
+
+	setCursorLinks(w, r, next)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// userIDFromClaims reads the user of the token, writing the error response
+// when the claim is missing.
+func userIDFromClaims(w http.ResponseWriter, r *http.Request) (entityPkg.ID, bool) {
+	if actorID := actorFromClaims(r); actorID != nil {
+		return *actorID, true
+	}
+	problem.Write(w, r, problem.Forbidden("invalid token: missing 'sub'"))
+	return entityPkg.ID{}, false
+}
+
+// actorFromClaims returns the user of the token, if any, to be recorded as
+// the author of a change.
+func actorFromClaims(r *http.Request) *entityPkg.ID {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	return &principal.UserID
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// parsePage reads the page and limit query parameters, falling back to the
+// first page and the given default limit. The limit is capped at
+// maxPageLimit.
+func parsePage(r *http.Request, defaultLimit int) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return page, limit
+}
+
+// setPageLinks sets the RFC 5988 Link header with the first, prev, next and
+// last pages, keeping the other query parameters of the request.
+func setPageLinks(w http.ResponseWriter, r *http.Request, page, limit int, total int64) {
+	last := int((total + int64(limit) - 1) / int64(limit))
+	if last < 1 {
+		last = 1
+	}
+
+	links := []string{pageLink(r, 1, limit, "first")}
+	if page > 1 {
+		links = append(links, pageLink(r, page-1, limit, "prev"))
+	}
+	if page < last {
+		links = append(links, pageLink(r, page+1, limit, "next"))
+	}
+	links = append(links, pageLink(r, last, limit, "last"))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageLink(r *http.Request, page, limit int, rel string) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	return fmt.Sprintf(`<%s>; rel="%s"`, requestURL(r, query), rel)
+}
+
+// requestURL rebuilds the absolute URL of the request with another query.
+func requestURL(r *http.Request, query url.Values) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// setCursorLinks sets the RFC 5988 Link header of a list paged by cursor:
+// the first page and, when there is one, the next.
+func setCursorLinks(w http.ResponseWriter, r *http.Request, next string) {
+	query := r.URL.Query()
+	query.Del("after")
+	query.Del("page")
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, requestURL(r, query))}
+
+	if next != "" {
+		query.Set("after", next)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, requestURL(r, query)))
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// parseCursor reads the after query parameter, writing the error response
+// when the cursor is invalid.
+func parseCursor(w http.ResponseWriter, r *http.Request, codec *cursor.Codec) (*cursor.Cursor, bool) {
+	after := r.URL.Query().Get("after")
+	if after == "" {
+		return nil, true
+	}
+
+	cur, err := codec.Decode(after)
+	if err != nil {
+		problem.Write(w, r, problem.BadRequest("invalid cursor"))
+		return nil, false
+	}
+	return cur, true
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/mailer"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/ratelimit"
+)
+
+const (
+	// mailRequestDelay is the least time the endpoints that email an account
+	// take, so the response time doesn't tell whether the email is registered.
+	mailRequestDelay = 500 * time.Millisecond
+	// Each endpoint sends at most mailRequestLimit emails per hour to an
+	// address.
+	mailRequestLimit = 3
+)
+
+var errInvalidResetToken = problem.BadRequest("invalid or expired reset token")
+
+type PasswordHandler struct {
+	UserDb             database.UserInterface
+	PasswordResetDB    database.PasswordResetTokenInterface
+	RefreshTokenDB     database.RefreshTokenInterface
+	TokenRevocationDB  database.TokenRevocationInterface
+	Mailer             mailer.Mailer
+	ResetURL           string
+	ResetExpiresIn     int
+	forgotPasswordRate *ratelimit.Limiter
+}
+
+func NewPasswordHandler(userDB database.UserInterface, passwordResetDB database.PasswordResetTokenInterface, refreshTokenDB database.RefreshTokenInterface, tokenRevocationDB database.TokenRevocationInterface, m mailer.Mailer, resetURL string, resetExpiresIn int) *PasswordHandler {
+	return &PasswordHandler{
+		UserDb:             userDB,
+		PasswordResetDB:    passwordResetDB,
+		RefreshTokenDB:     refreshTokenDB,
+		TokenRevocationDB:  tokenRevocationDB,
+		Mailer:             m,
+		ResetURL:           resetURL,
+		ResetExpiresIn:     resetExpiresIn,
+		forgotPasswordRate: ratelimit.New(mailRequestLimit, time.Hour),
+	}
+}
+
+// ForgotPassword godoc
+// @Summary Ask for a password reset link
+// @Description Email a single-use link to choose a new password. The answer is the same whether the email is
+// @Description registered or not.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body dto.ForgotPasswordInput true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Router /auth/password/forgot [post]
+func (ph *PasswordHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	start := time.Now()
+
+	var input dto.ForgotPasswordInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	// O limite vale para qualquer email, cadastrado ou não
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if ok, retryAfter := ph.forgotPasswordRate.Allow(email); !ok {
+		writeTooManyRequests(w, r, retryAfter, "too many reset requests for this email, try again later")
+		return
+	}
+
+	if err := ph.sendResetLink(input.Email); err != nil {
+		// Falhas só vão para o log, para não revelar se a conta existe
+		log.Printf("Erro ao gerar o link de redefinição de senha: %v\n", err)
+	}
+
+	time.Sleep(time.Until(start.Add(mailRequestDelay)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the email is registered, a reset link was sent to it"})
+}
+
+// sendResetLink creates a reset token for the account of the email, if there
+// is an enabled one, and emails it in the background so that the delivery
+// time doesn't show in the response.
+func (ph *PasswordHandler) sendResetLink(email string) error {
+	user, err := ph.UserDb.FindUserByEmail(email)
+	if err != nil || user.Disabled {
+		return nil
+	}
+
+	token, plain, err := entity.NewPasswordResetToken(user.ID, time.Duration(ph.ResetExpiresIn)*time.Second)
+	if err != nil {
+		return err
+	}
+
+	if err := ph.PasswordResetDB.CreatePasswordResetToken(token); err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(ph.ResetURL, plain)
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes and works only once.\n\n%s\n\nIf you didn't ask for it, you can ignore this email.\n",
+			user.Name, ph.ResetExpiresIn/60, link),
+	}
+
+	go func() {
+		if err := ph.Mailer.Send(msg); err != nil {
+			log.Printf("Erro ao enviar o email de redefinição de senha para %s: %v\n", msg.To, err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Choose a new password with the token of a reset link. The token works once, and every open session
+// @Description of the account is logged out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body dto.ResetPasswordInput true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /auth/password/reset [post]
+func (ph *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.ResetPasswordInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	token, err := ph.PasswordResetDB.FindPasswordResetTokenByHash(entityPkg.HashToken(input.Token))
+	if err != nil || token.Validate() != nil {
+		problem.Write(w, r, errInvalidResetToken)
+		return
+	}
+
+	marked, err := ph.PasswordResetDB.MarkPasswordResetTokenUsed(token.ID.String())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	if !marked {
+		// Outra requisição usou o mesmo token ao mesmo tempo
+		problem.Write(w, r, errInvalidResetToken)
+		return
+	}
+
+	user, err := ph.UserDb.FindUserById(token.UserID.String())
+	if err != nil || user.Disabled {
+		problem.Write(w, r, errInvalidResetToken)
+		return
+	}
+
+	if err := user.ChangePassword(input.Password); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// Quem recebeu o link provou ser o dono da conta, então o bloqueio por
+	// senhas erradas acaba junto
+	user.Unlock()
+
+	if err := ph.UserDb.UpdateUser(user); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	// Links antigos e sessões abertas deixam de valer com a senha nova
+	if err := ph.PasswordResetDB.InvalidateUserPasswordResetTokens(user.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if err := revokeSessions(ph.TokenRevocationDB, ph.RefreshTokenDB, user.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "password changed successfully"})
+}
+
+// linkWithToken adds the token to the query of the page URL.
+func linkWithToken(page, token string) (string, error) {
+	link, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// writeTooManyRequests answers 429 telling the client when to try again.
+func writeTooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	problem.Write(w, r, problem.TooManyRequests(msg))
+}
@@ -1,56 +1,111 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/search"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/patch"
 	"gorm.io/gorm"
 )
 
 type ProductHandler struct {
-	ProductDB database.ProductInterface
+	ProductDB   database.ProductInterface
+	CategoryDB  database.CategoryInterface
+	SearchIndex *search.Index
+	Cursors     *cursor.Codec
 }
 
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
+func NewProductHandler(db database.ProductInterface, categoryDB database.CategoryInterface, searchIndex *search.Index, cursors *cursor.Codec) *ProductHandler {
 	return &ProductHandler{
-		ProductDB: db,
+		ProductDB:   db,
+		CategoryDB:  categoryDB,
+		SearchIndex: searchIndex,
+		Cursors:     cursors,
 	}
 }
 
+var errCategoryNotFound = problem.Validation("category not found", problem.FieldError{Field: "category_ids", Message: "has categories that don't exist"})
+
+// findCategories loads the categories with the given IDs, failing when any of
+// them does not exist.
+func (ph *ProductHandler) findCategories(ids []string) ([]entity.Category, error) {
+	unique := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+
+	categories, err := ph.CategoryDB.FindCategoriesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(categories) != len(unique) {
+		return nil, errCategoryNotFound
+	}
+
+	return categories, nil
+}
+
 func (ph *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var productInput dto.CreateProductInput
 
 	// Fechar o corpo da requisição após uso
 	defer r.Body.Close()
 
-	err := json.NewDecoder(r.Body).Decode(&productInput)
+	err := decodeJSON(r, &productInput)
 
 	if err != nil {
-		http.Error(w, `{"error": "invalid JSON format"}`, http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
 	p, err := entity.NewProduct(productInput.Name, productInput.Price)
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
+	p.LowStockThreshold = productInput.LowStockThreshold
+	if err := p.ValidateProduct(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if len(productInput.CategoryIDs) > 0 {
+		categories, err := ph.findCategories(productInput.CategoryIDs)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		p.Categories = categories
+	}
+
 	err = ph.ProductDB.CreateProduct(p)
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
+	ph.indexProduct(p)
+
 	json.NewEncoder(w).Encode(map[string]string{"message": "product created successfully"})
 }
 
@@ -66,33 +121,42 @@ func (ph *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request)
 // @Security ApiKeyAuth
 // GetProduct godoc
 // @Summary Get a product
-// @Description Retrieve a product by its ID
+// @Description Retrieve a product by its ID. The ETag header carries its version; sending it back in
+// @Description If-None-Match answers 304 while the product is unchanged.
 // @Tags products
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} entity.Product
-// @Failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Success 304
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /product/{id} [get]
 func (ph *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close() // Garante que qualquer recurso seja fechado
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, `{"error": "missing product ID"}`, http.StatusBadRequest)
+		problem.Write(w, r, problem.BadRequest("missing product ID"))
 		return
 	}
 
 	product, err := ph.ProductDB.FindProductByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"error": "product not found"}`, http.StatusNotFound)
+			problem.Write(w, r, problem.NotFound("product not found"))
 		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+			problem.Write(w, r, problem.Internal(err))
 		}
 		return
 	}
 
+	etag := productETag(product)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	w.Header().Set("ETag", etag)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(product)
@@ -100,16 +164,19 @@ func (ph *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 
 // UpdateProduct godoc
 // @Summary Update an existing product
-// @Description Update product identified by ID
+// @Description Update product identified by ID. If-Match is optional: with it, the update only happens if the
+// @Description product is still at that ETag; either way, a concurrent change answers 412.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag the changes were based on"
 // @Param product body dto.UpdateProductInput true "Product data"
 // @Success 200 {object} entity.Product
-// @Failure 400 {object} Error
-// @Failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /admin/product/{id} [put]
 // @Security ApiKeyAuth
 func (ph *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
@@ -117,42 +184,214 @@ func (ph *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request)
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, `{"error": "missing product ID"}`, http.StatusBadRequest)
+		problem.Write(w, r, problem.BadRequest("missing product ID"))
 		return
 	}
 
 	var input dto.UpdateProductInput
-	err := json.NewDecoder(r.Body).Decode(&input)
+	err := decodeJSON(r, &input)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
 	product, err := ph.ProductDB.FindProductByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"error": "product not found"}`, http.StatusNotFound)
+			problem.Write(w, r, problem.NotFound("product not found"))
 		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+			problem.Write(w, r, problem.Internal(err))
 		}
 		return
 	}
 
+	if err := checkIfMatch(r, productETag(product)); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
 	// Verifica se o campo foi setado no corpo da requisição
 	if input.Name != "" {
 		product.Name = input.Name
 	}
 
-	if input.Price != 0.0 {
-		product.Price = input.Price
+	if input.Price != nil {
+		product.Price = *input.Price
+	}
+
+	if input.LowStockThreshold != nil {
+		product.LowStockThreshold = *input.LowStockThreshold
+	}
+
+	ph.saveProduct(w, r, product, input.CategoryIDs)
+}
+
+// PatchProduct godoc
+// @Summary Partially update a product
+// @Description Apply a JSON Merge Patch (application/merge-patch+json, RFC 7396) or a JSON Patch
+// @Description (application/json-patch+json, RFC 6902) to the product. The patch works on the fields name,
+// @Description price, category_ids and low_stock_threshold, and the result is validated as a whole before saving.
+// @Description If-Match is optional, as on PUT.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag the changes were based on"
+// @Param patch body dto.ProductDocument true "Merge patch, or a list of JSON Patch operations"
+// @Success 200 {object} entity.Product
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/{id} [patch]
+// @Security ApiKeyAuth
+func (ph *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := chi.URLParam(r, "id")
+
+	var applyPatch func(doc, changes []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case patch.MergePatchType:
+		applyPatch = patch.Merge
+	case patch.JSONPatchType:
+		applyPatch = patch.Apply
+	default:
+		w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		problem.Write(w, r, problem.UnsupportedMediaType(fmt.Sprintf("use %s or %s", patch.MergePatchType, patch.JSONPatchType)))
+		return
+	}
+
+	changes, err := io.ReadAll(r.Body)
+	if err != nil {
+		problem.Write(w, r, problem.BadRequest("could not read the request body"))
+		return
+	}
+	if len(changes) == 0 {
+		problem.Write(w, r, problem.BadRequest("request body is empty"))
+		return
+	}
+
+	product, err := ph.ProductDB.FindProductByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("product not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	if err := checkIfMatch(r, productETag(product)); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	current := productDocument(product)
+	doc, err := json.Marshal(current)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
 	}
 
-	err = ph.ProductDB.UpdateProduct(product)
+	doc, err = applyPatch(doc, changes)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusInternalServerError)
+		problem.Write(w, r, err)
+		return
+	}
+
+	// O resultado precisa continuar sendo um produto válido
+	var patched dto.ProductDocument
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		if e := problem.From(err); e.Kind != problem.KindInternal {
+			problem.Write(w, r, e)
+		} else {
+			problem.Write(w, r, problem.Validation("the patched product is invalid: "+err.Error()))
+		}
 		return
 	}
 
+	product.Name = patched.Name
+	product.Price = patched.Price
+	product.LowStockThreshold = patched.LowStockThreshold
+
+	// Só troca as categorias quando o patch mexeu nelas
+	var categoryIDs []string
+	if !sameIDs(current.CategoryIDs, patched.CategoryIDs) {
+		categoryIDs = patched.CategoryIDs
+		if categoryIDs == nil {
+			categoryIDs = []string{}
+		}
+	}
+
+	ph.saveProduct(w, r, product, categoryIDs)
+}
+
+// productDocument is the product as seen by PATCH requests.
+func productDocument(product *entity.Product) dto.ProductDocument {
+	categoryIDs := make([]string, 0, len(product.Categories))
+	for _, category := range product.Categories {
+		categoryIDs = append(categoryIDs, category.ID.String())
+	}
+	return dto.ProductDocument{
+		Name:              product.Name,
+		Price:             product.Price,
+		CategoryIDs:       categoryIDs,
+		LowStockThreshold: product.LowStockThreshold,
+	}
+}
+
+// sameIDs reports whether both lists hold the same IDs, in any order.
+func sameIDs(a, b []string) bool {
+	set := make(map[string]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	other := make(map[string]bool, len(b))
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+		other[id] = true
+	}
+	return len(set) == len(other)
+}
+
+// saveProduct validates and stores the changed product and writes it back
+// with its new ETag. A nil categoryIDs keeps the current categories, while
+// an empty list removes all of them.
+func (ph *ProductHandler) saveProduct(w http.ResponseWriter, r *http.Request, product *entity.Product, categoryIDs []string) {
+	if err := product.ValidateProduct(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	var categories []entity.Category
+	if categoryIDs != nil {
+		var err error
+		categories, err = ph.findCategories(categoryIDs)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		// Uma lista vazia remove todas as categorias
+		if categories == nil {
+			categories = []entity.Category{}
+		}
+	}
+
+	if err := ph.ProductDB.UpdateProduct(product, categories); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	ph.indexProduct(product)
+
+	w.Header().Set("ETag", productETag(product))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(product)
@@ -160,77 +399,420 @@ func (ph *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request)
 
 // DeleteProduct godoc
 // @Summary Delete a product
-// @Description Delete a product by ID
+// @Description Move a product to the trash. It can be restored until it is purged. If-Match is optional: with it,
+// @Description the product is only removed if it is still at that ETag; either way, a concurrent change answers 412.
 // @Tags products
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag the product must still have"
 // @Success 200 {object} map[string]string
-// @Failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Failure 404 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /admin/product/{id} [delete]
 // @Security ApiKeyAuth
 func (ph *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, `{"error": "missing product ID"}`, http.StatusBadRequest)
+		problem.Write(w, r, problem.BadRequest("missing product ID"))
+		return
+	}
+
+	product, err := ph.ProductDB.FindProductByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("product not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	if err := checkIfMatch(r, productETag(product)); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	err := ph.ProductDB.DeleteProduct(id)
+	// Só remove a versão que foi lida, para não levar junto uma edição
+	// feita no meio
+	err = ph.ProductDB.DeleteProduct(product, actorFromClaims(r))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"error": "product not found"}`, http.StatusNotFound)
+			problem.Write(w, r, problem.NotFound("product not found"))
 		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+			problem.Write(w, r, err)
 		}
 		return
 	}
+
+	if ph.SearchIndex != nil {
+		ph.SearchIndex.Remove(id)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "product deleted successfully"})
 }
 
+// GetTrash godoc
+// @Summary List deleted products
+// @Description Get a page of the products in the trash, the latest removed first
+// @Tags products
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Limit (max 100)"
+// @Success 200 {object} dto.ProductListOutput
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/trash [get]
+// @Security ApiKeyAuth
+func (ph *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	page, limit := parsePage(r, defaultPageLimit)
+
+	products, total, err := ph.ProductDB.FindDeletedProducts(page, limit)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	setPageLinks(w, r, page, limit, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ProductListOutput{
+		Items:   products,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		HasNext: int64(page*limit) < total,
+	})
+}
+
+// RestoreProduct godoc
+// @Summary Restore a product
+// @Description Take a product out of the trash, with its categories and stock
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} entity.Product
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/{id}/restore [post]
+// @Security ApiKeyAuth
+func (ph *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	product, err := ph.ProductDB.RestoreProduct(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = problem.NotFound("product not found in the trash")
+		}
+		problem.Write(w, r, err)
+		return
+	}
+
+	ph.indexProduct(product)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// PurgeProduct godoc
+// @Summary Purge a product
+// @Description Delete a product of the trash for good. Only admins can purge.
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/{id}/purge [delete]
+// @Security ApiKeyAuth
+func (ph *ProductHandler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	err := ph.ProductDB.PurgeProduct(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = problem.NotFound("product not found in the trash")
+		}
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "product purged successfully"})
+}
+
 // GetProducts godoc
 // @Summary List products
-// @Description Get products with pagination
+// @Description Get a page of products with filters and sorting. The response carries the total of matching products
+// @Description and a Link header (RFC 5988) with the first, prev, next and last pages. When sorted by creation date
+// @Description only, next_cursor can be sent as `after` to page through large catalogs without offsets.
 // @Tags products
 // @Produce json
 // @Param page query int false "Page number"
-// @Param limit query int false "Limit"
-// @Param sort query string false "Sort order"
-// @Success 200 {array} entity.Product
-// @Failure 500 {object} Error
+// @Param after query string false "Cursor from next_cursor; pages by creation date without offsets and ignores page and sort"
+// @Param limit query int false "Limit (max 100)"
+// @Param sort query string false "Comma separated sort keys, '-' for descending: created_at, name, price, stock (e.g. -price,name)"
+// @Param category query string false "Category ID"
+// @Param include_descendants query bool false "Also match products of subcategories"
+// @Param in_stock query bool false "Only products with (true) or without (false) stock"
+// @Param price_min query string false "Minimum price, e.g. 10.00 or '10.00 USD'"
+// @Param price_max query string false "Maximum price, e.g. 99.90 or '99.90 USD'"
+// @Param name_contains query string false "Part of the name, case insensitive"
+// @Param created_after query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Success 200 {object} dto.ProductListOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /product [get]
 func (ph *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	page := r.URL.Query().Get("page")
-	limit := r.URL.Query().Get("limit")
-	sort := r.URL.Query().Get("sort")
+	query, err := ph.parseProductQuery(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	limit := query.Limit
+	if query.After != nil {
+		// Busca um item a mais para saber se existe próxima página
+		query.Limit++
+	}
+
+	products, total, err := ph.ProductDB.FindAllProducts(query)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	output := dto.ProductListOutput{Total: total, Limit: limit}
+
+	if query.After != nil {
+		output.HasNext = len(products) > limit
+		if output.HasNext {
+			products = products[:limit]
+		}
+	} else {
+		output.Page = query.Page
+		output.HasNext = int64(query.Page*limit) < total
+	}
+	output.Items = products
+
+	// O cursor só vale para a ordenação por data de criação
+	if desc, ok := keysetOrder(query); ok && output.HasNext {
+		last := products[len(products)-1]
+		output.NextCursor = ph.Cursors.Encode(cursor.Cursor{CreatedAt: last.CreatedAt, ID: last.ID.String(), Desc: desc})
+	}
+
+	if query.After != nil {
+		setCursorLinks(w, r, output.NextCursor)
+	} else {
+		setPageLinks(w, r, query.Page, limit, total)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// keysetOrder reports whether the query is ordered by creation date only,
+// the order cursors follow, and in which direction.
+func keysetOrder(query database.ProductQuery) (bool, bool) {
+	if query.After != nil {
+		return query.After.Desc, true
+	}
+	if len(query.Sort) == 1 && query.Sort[0].Field == "created_at" {
+		return query.Sort[0].Desc, true
+	}
+	return false, false
+}
+
+// parseProductQuery reads the whitelisted filters and sort keys of GET
+// /product.
+func (ph *ProductHandler) parseProductQuery(r *http.Request) (database.ProductQuery, error) {
+	params := r.URL.Query()
 
-	pageInt, err := strconv.Atoi(page)
+	var query database.ProductQuery
+	query.Page, query.Limit = parsePage(r, defaultPageLimit)
 
-	if err != nil || pageInt < 1 {
-		pageInt = 1
+	if after := params.Get("after"); after != "" {
+		cur, err := ph.Cursors.Decode(after)
+		if err != nil {
+			return query, problem.BadRequest("invalid cursor")
+		}
+		query.After = cur
 	}
 
-	limitInt, err := strconv.Atoi(limit)
-	if err != nil || limitInt < 1 {
-		limitInt = 10 // Define um limite padrão
+	sort, err := parseProductSort(params.Get("sort"))
+	if err != nil {
+		return query, err
 	}
+	query.Sort = sort
 
-	// Segurança na ordenação
-	validSortOptions := map[string]bool{"asc": true, "desc": true}
-	if !validSortOptions[sort] {
-		sort = "asc"
+	if category := params.Get("category"); category != "" {
+		query.CategoryIDs = []string{category}
+
+		if includeDescendants, _ := strconv.ParseBool(params.Get("include_descendants")); includeDescendants {
+			descendants, err := ph.CategoryDB.FindDescendantIDs(category)
+			if err != nil {
+				return query, invalidParam("category", "is not a valid category")
+			}
+			query.CategoryIDs = append(query.CategoryIDs, descendants...)
+		}
+	}
+
+	if inStock := params.Get("in_stock"); inStock != "" {
+		value, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return query, invalidParam("in_stock", "must be true or false")
+		}
+		query.InStock = &value
+	}
+
+	for name, target := range map[string]**entityPkg.Money{"price_min": &query.PriceMin, "price_max": &query.PriceMax} {
+		if value := params.Get(name); value != "" {
+			price, err := entityPkg.ParseMoneyText(value)
+			if err != nil {
+				return query, invalidParam(name, "must be an amount, optionally followed by a currency")
+			}
+			*target = &price
+		}
+	}
+	if query.PriceMin != nil && query.PriceMax != nil && query.PriceMin.Currency != query.PriceMax.Currency {
+		return query, invalidParam("price_max", "must use the same currency as price_min")
 	}
 
-	products, err := ph.ProductDB.FindAllProducts(pageInt, limitInt, sort)
+	query.NameContains = strings.TrimSpace(params.Get("name_contains"))
+
+	for name, target := range map[string]**time.Time{"created_after": &query.CreatedAfter, "created_before": &query.CreatedBefore} {
+		if value := params.Get(name); value != "" {
+			date, err := parseDate(value)
+			if err != nil {
+				return query, invalidParam(name, "must be a RFC 3339 date or YYYY-MM-DD")
+			}
+			*target = &date
+		}
+	}
+
+	return query, nil
+}
+
+// parseProductSort reads sort keys like "-price,name". The legacy values
+// "asc" and "desc" sort by creation date.
+func parseProductSort(value string) ([]database.ProductSort, error) {
+	switch value {
+	case "", "asc":
+		return []database.ProductSort{{Field: "created_at"}}, nil
+	case "desc":
+		return []database.ProductSort{{Field: "created_at", Desc: true}}, nil
+	}
+
+	var sort []database.ProductSort
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		field := strings.TrimPrefix(key, "-")
+		if _, ok := database.ProductSortColumns[field]; !ok {
+			return nil, invalidParam("sort", fmt.Sprintf("'%s' is not a sortable field", field))
+		}
+		sort = append(sort, database.ProductSort{Field: field, Desc: strings.HasPrefix(key, "-")})
+	}
+	return sort, nil
+}
+
+// parseDate accepts RFC 3339 timestamps or plain dates (midnight UTC).
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// SearchProducts godoc
+// @Summary Search products
+// @Description Full text search over the product names. Matching ignores case and accents and every word must match,
+// @Description whole or as a prefix for typeahead. Results come ranked by relevance with highlighted snippets.
+// @Tags products
+// @Produce json
+// @Param q query string true "Search terms"
+// @Param limit query int false "Limit of results"
+// @Success 200 {array} dto.ProductSearchResult
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /product/search [get]
+func (ph *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		problem.Write(w, r, problem.BadRequest("missing search query"))
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	hits := ph.SearchIndex.Search(query, limit)
+
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	products, err := ph.ProductDB.FindProductsByIDs(ids)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusInternalServerError)
+		problem.Write(w, r, problem.Internal(err))
 		return
 	}
 
+	byID := make(map[string]entity.Product, len(products))
+	for _, product := range products {
+		byID[product.ID.String()] = product
+	}
+
+	// Mantém a ordem de relevância do índice
+	results := make([]dto.ProductSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		product, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, dto.ProductSearchResult{
+			Product:    product,
+			Score:      hit.Score,
+			Highlights: hit.Highlights,
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(results)
+}
+
+// RebuildSearchIndex loads every product into the search index. It runs on
+// startup, since the index lives in memory.
+func (ph *ProductHandler) RebuildSearchIndex() error {
+	const batch = 500
+
+	for page := 1; ; page++ {
+		products, _, err := ph.ProductDB.FindAllProducts(database.ProductQuery{Page: page, Limit: batch})
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			ph.indexProduct(&products[i])
+		}
+		if len(products) < batch {
+			return nil
+		}
+	}
+}
+
+func (ph *ProductHandler) indexProduct(product *entity.Product) {
+	if ph.SearchIndex == nil {
+		return
+	}
+	ph.SearchIndex.Add(product.ID.String(),
+		search.Field{Name: "name", Text: product.Name, Weight: 1},
+	)
 }
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+)
+
+// Instância do validador globalmente
+var validate = newValidator()
+
+// newValidator reports fields by their JSON name, the one clients know.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// decodeJSON reads the request body into v. Malformed bodies are a bad
+// request; values rejected by their own decoders, like money amounts, keep
+// their validation error.
+func decodeJSON(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return problem.BadRequest("request body is empty")
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if e := problem.From(err); e.Kind != problem.KindInternal {
+			return e
+		}
+		return problem.BadRequest("invalid JSON format")
+	}
+	return nil
+}
+
+// validateInput runs the struct validation and writes the field errors.
+func validateInput(w http.ResponseWriter, r *http.Request, input interface{}) bool {
+	err := validate.Struct(input)
+	if err == nil {
+		return true
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		problem.Write(w, r, problem.Internal(err))
+		return false
+	}
+
+	fields := make([]problem.FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, problem.FieldError{
+			Field:   fieldPath(fieldErr.Namespace()),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+
+	problem.Write(w, r, problem.Validation("invalid input", fields...))
+	return false
+}
+
+// fieldPath drops the struct name from the namespace, so
+// "CreateOrderInput.items[0].quantity" becomes "items[0].quantity".
+func fieldPath(namespace string) string {
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fieldErr.Param())
+	default:
+		return "is invalid"
+	}
+}
+
+// invalidParam reports an invalid query parameter.
+func invalidParam(name, message string) error {
+	return problem.Validation(fmt.Sprintf("invalid %s", name), problem.FieldError{Field: name, Message: message})
+}
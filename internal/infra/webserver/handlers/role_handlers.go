@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"gorm.io/gorm"
+)
+
+type RoleHandler struct {
+	RoleDB       database.RoleInterface
+	PermissionDB database.PermissionInterface
+}
+
+func NewRoleHandler(roleDB database.RoleInterface, permissionDB database.PermissionInterface) *RoleHandler {
+	return &RoleHandler{
+		RoleDB:       roleDB,
+		PermissionDB: permissionDB,
+	}
+}
+
+var errPermissionNotFound = problem.Validation("permission not found", problem.FieldError{Field: "permissions", Message: "has permissions that don't exist"})
+
+// findPermissions loads the permissions with the given names, failing when
+// any of them does not exist.
+func findPermissions(permissionDB database.PermissionInterface, names []string) ([]entity.Permission, error) {
+	unique := make(map[string]bool, len(names))
+	for _, name := range names {
+		unique[name] = true
+	}
+
+	permissions, err := permissionDB.FindPermissionsByNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(permissions) != len(unique) {
+		return nil, errPermissionNotFound
+	}
+
+	return permissions, nil
+}
+
+// GetPermissions godoc
+// @Summary List permissions
+// @Description List every permission that can be granted to roles
+// @Tags admin roles
+// @Produce json
+// @Success 200 {array} entity.Permission
+// @Failure 500 {object} problem.Problem
+// @Router /admin/permission [get]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := rh.PermissionDB.FindAllPermissions()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(permissions)
+}
+
+// GetRoles godoc
+// @Summary List roles
+// @Description List every role with its permissions
+// @Tags admin roles
+// @Produce json
+// @Success 200 {array} entity.Role
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role [get]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := rh.RoleDB.FindAllRoles()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(roles)
+}
+
+// GetRole godoc
+// @Summary Get a role
+// @Description Retrieve a role and its permissions by ID
+// @Tags admin roles
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 200 {object} entity.Role
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role/{id} [get]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	role, ok := rh.loadRole(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Create a role granting it the given permissions
+// @Tags admin roles
+// @Accept json
+// @Produce json
+// @Param role body dto.CreateRoleInput true "Role data"
+// @Success 201 {object} entity.Role
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role [post]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.CreateRoleInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	if err := rh.checkNameAvailable(input.Name); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	permissions, err := findPermissions(rh.PermissionDB, input.Permissions)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	role, err := entity.NewRole(input.Name)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := rh.RoleDB.CreateRole(role); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if err := rh.RoleDB.SetRolePermissions(role, permissions); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRole godoc
+// @Summary Rename a role
+// @Description Rename a role. Built-in roles can't be renamed.
+// @Tags admin roles
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param role body dto.UpdateRoleInput true "Role data"
+// @Success 200 {object} entity.Role
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role/{id} [put]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.UpdateRoleInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	role, ok := rh.loadRole(w, r)
+	if !ok {
+		return
+	}
+
+	if input.Name != role.Name {
+		if err := rh.checkNameAvailable(input.Name); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+	}
+
+	if err := role.Rename(input.Name); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := rh.RoleDB.UpdateRole(role); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// SetRolePermissions godoc
+// @Summary Set the permissions of a role
+// @Description Replace the permissions granted to a role. The admin role always keeps every permission.
+// @Tags admin roles
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param permissions body dto.SetRolePermissionsInput true "Permission names"
+// @Success 200 {object} entity.Role
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role/{id}/permissions [put]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.SetRolePermissionsInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	role, ok := rh.loadRole(w, r)
+	if !ok {
+		return
+	}
+
+	if err := role.CanChangePermissions(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	permissions, err := findPermissions(rh.PermissionDB, input.Permissions)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := rh.RoleDB.SetRolePermissions(role, permissions); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// SetRoleMFA godoc
+// @Summary Require two-factor authentication for a role
+// @Description Turn on or off the two-factor authentication required by a role. Users of a role that requires it
+// @Description are kept out of the admin routes until they log in with a second factor.
+// @Tags admin roles
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param mfa body dto.SetRoleMFAInput true "Whether MFA is required"
+// @Success 200 {object} entity.Role
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role/{id}/mfa [put]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) SetRoleMFA(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.SetRoleMFAInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	role, ok := rh.loadRole(w, r)
+	if !ok {
+		return
+	}
+
+	if err := rh.RoleDB.SetRoleRequireMFA(role.ID.String(), *input.Required); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+	role.RequireMFA = *input.Required
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(role)
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @Description Delete a role that no user has. Built-in roles can't be deleted.
+// @Tags admin roles
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/role/{id} [delete]
+// @Security ApiKeyAuth
+func (rh *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	role, ok := rh.loadRole(w, r)
+	if !ok {
+		return
+	}
+
+	if role.IsBuiltIn() {
+		problem.Write(w, r, entity.ErrBuiltInRole)
+		return
+	}
+
+	if err := rh.RoleDB.DeleteRole(role.ID.String()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = problem.NotFound("role not found")
+		}
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "role deleted successfully"})
+}
+
+func (rh *RoleHandler) loadRole(w http.ResponseWriter, r *http.Request) (*entity.Role, bool) {
+	role, err := rh.RoleDB.FindRoleByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("role not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return nil, false
+	}
+	return role, true
+}
+
+func (rh *RoleHandler) checkNameAvailable(name string) error {
+	exists, err := rh.RoleDB.RoleExists(name)
+	if err != nil {
+		return problem.Internal(err)
+	}
+	if exists {
+		return entity.ErrRoleNameIsTaken
+	}
+	return nil
+}
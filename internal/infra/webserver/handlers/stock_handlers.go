@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type StockHandler struct {
+	StockDB   database.StockInterface
+	ProductDB database.ProductInterface
+}
+
+func NewStockHandler(stockDB database.StockInterface, productDB database.ProductInterface) *StockHandler {
+	return &StockHandler{
+		StockDB:   stockDB,
+		ProductDB: productDB,
+	}
+}
+
+// GetStock godoc
+// @Summary Get product stock
+// @Description Get the current stock of a product and its latest stock movements
+// @Tags stock
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param page query int false "Page number of the movements"
+// @Param limit query int false "Limit of movements"
+// @Success 200 {object} dto.StockOutput
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/{id}/stock [get]
+// @Security ApiKeyAuth
+func (sh *StockHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	product, err := sh.ProductDB.FindProductByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("product not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	page, limit := parsePage(r, 20)
+
+	movements, err := sh.StockDB.FindStockMovements(id, page, limit)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.StockOutput{
+		ProductID:         product.ID.String(),
+		Stock:             product.Stock,
+		LowStockThreshold: product.LowStockThreshold,
+		LowStock:          product.IsLowStock(),
+		Movements:         movements,
+	})
+}
+
+// AdjustStock godoc
+// @Summary Adjust product stock
+// @Description Register a stock movement (receipt, sale, adjustment or return) and apply it atomically.
+// @Description Receipts, sales and returns take a positive quantity; adjustments take the signed difference and require a reason.
+// @Tags stock
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param movement body dto.AdjustStockInput true "Stock movement"
+// @Success 201 {object} entity.StockMovement
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/{id}/stock [post]
+// @Security ApiKeyAuth
+func (sh *StockHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := chi.URLParam(r, "id")
+
+	productID, err := entityPkg.ParseID(id)
+	if err != nil {
+		problem.Write(w, r, problem.NotFound("product not found"))
+		return
+	}
+
+	var input dto.AdjustStockInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	movement, err := entity.NewStockMovement(productID, entity.StockMovementType(input.Type), input.Quantity, input.Reason, actorFromClaims(r))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = sh.StockDB.AdjustStock(movement)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			problem.Write(w, r, problem.NotFound("product not found"))
+		case errors.Is(err, entity.ErrInsufficientStock):
+			problem.Write(w, r, err)
+		default:
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// GetLowStockProducts godoc
+// @Summary List low stock products
+// @Description Get the products whose stock is at or below their low stock threshold
+// @Tags stock
+// @Produce json
+// @Success 200 {array} entity.Product
+// @Failure 500 {object} problem.Problem
+// @Router /admin/product/low-stock [get]
+// @Security ApiKeyAuth
+func (sh *StockHandler) GetLowStockProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := sh.StockDB.FindLowStockProducts()
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(products)
+}
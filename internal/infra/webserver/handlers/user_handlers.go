@@ -4,120 +4,391 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
-	"github.com/go-chi/jwtauth"
-	"github.com/go-playground/validator"
 	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
 	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	_ "github.com/mateusfaustino/go-rest-api-III/internal/entity"
 	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
 	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/jwtkeys"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/ratelimit"
 	"gorm.io/gorm"
 )
 
-type Error struct {
-	Message string `json:"message"`
-}
-
 type UserHandler struct {
-	UserDb       database.UserInterface
-	RoleDB       database.RoleInterface
-	Jwt          *jwtauth.JWTAuth
-	JwtExpiresIn int
+	UserDb              database.UserInterface
+	RoleDB              database.RoleInterface
+	RefreshTokenDB      database.RefreshTokenInterface
+	TokenRevocationDB   database.TokenRevocationInterface
+	RecoveryCodeDB      database.MFARecoveryCodeInterface
+	Jwt                 *jwtkeys.KeySet
+	JwtExpiresIn        int
+	JwtRefreshExpiresIn int
+	Verification        EmailVerification
+	MFA                 MFAConfig
+	Login               LoginProtection
+
+	resendVerificationRate *ratelimit.Limiter
+	mfaAttemptRate         *ratelimit.Limiter
+	loginIPBackoff         *ratelimit.Backoff
 }
 
 type AccessTokenResponse struct {
 	AccessToken string `json:"access_token"`
 }
 
-func NewUserHandler(db database.UserInterface, roleDB database.RoleInterface, jwt *jwtauth.JWTAuth, jwtExpiresIn int) *UserHandler {
+func NewUserHandler(db database.UserInterface, roleDB database.RoleInterface, refreshTokenDB database.RefreshTokenInterface, tokenRevocationDB database.TokenRevocationInterface, recoveryCodeDB database.MFARecoveryCodeInterface, jwt *jwtkeys.KeySet, jwtExpiresIn, jwtRefreshExpiresIn int, verification EmailVerification, mfa MFAConfig, login LoginProtection) *UserHandler {
 	return &UserHandler{
-		UserDb:       db,
-		RoleDB:       roleDB,
-		Jwt:          jwt,
-		JwtExpiresIn: jwtExpiresIn,
+		UserDb:              db,
+		RoleDB:              roleDB,
+		RefreshTokenDB:      refreshTokenDB,
+		TokenRevocationDB:   tokenRevocationDB,
+		RecoveryCodeDB:      recoveryCodeDB,
+		Jwt:                 jwt,
+		JwtExpiresIn:        jwtExpiresIn,
+		JwtRefreshExpiresIn: jwtRefreshExpiresIn,
+		Verification:        verification,
+		MFA:                 mfa,
+		Login:               login,
+
+		resendVerificationRate: ratelimit.New(mailRequestLimit, time.Hour),
+		mfaAttemptRate:         ratelimit.New(mfaAttemptLimit, mfaChallengeExpiresIn),
+		loginIPBackoff:         newLoginIPBackoff(),
 	}
 }
 
-// Instância do validador globalmente
-var validate = validator.New()
-
 // GetJWT godoc
 // @Summary: Get a JWT token
-// @Description: Get a JWT token with the given email and password
+// @Description: Get a JWT access token and a refresh token with the given email and password. Accounts with
+// @Description: two-factor authentication get a dto.MFAChallengeOutput instead, to finish at /auth/mfa/verify.
+// @Description: Wrong passwords slow down and then lock the account and the address, answering 429 with Retry-After.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body dto.GetJWTInput true "User data"
 // @Success 200 {object} dto.GetJWTOutput
-// @Failure 400 {object} Error
-// @Failure 401 {object} Error
-// @Failure 500 {object} Error
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /auth/login [post]
 func (uh *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if r.Body == nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "request body is empty"})
-		return
-	}
-
 	defer r.Body.Close()
 
 	var userInput dto.GetJWTInput
-	if err := json.NewDecoder(r.Body).Decode(&userInput); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JSON format"})
+	if err := decodeJSON(r, &userInput); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
 	if strings.TrimSpace(userInput.Email) == "" || strings.TrimSpace(userInput.Password) == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "email and password are required"})
+		problem.Write(w, r, problem.BadRequest("email and password are required"))
+		return
+	}
+
+	// Endereços com muitas falhas esperam antes de tentar de novo, qualquer que seja a conta
+	if wait := uh.loginIPBackoff.Wait(clientIP(r)); wait > 0 {
+		uh.recordLogin(r, nil, userInput.Email, entity.LoginThrottled)
+		writeTooManyRequests(w, r, wait, "too many failed logins from this address, try again later")
 		return
 	}
 
 	u, err := uh.UserDb.FindUserByEmail(userInput.Email)
 	if err != nil {
-		time.Sleep(500 * time.Millisecond) // Delay to prevent timing attacks
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid credentials"})
+		uh.failUnknownLogin(w, r, userInput.Email)
+		return
+	}
+
+	// Uma conta bloqueada nem confere a senha, senão o bloqueio não impediria os palpites
+	if wait := u.LoginLockedFor(time.Now()); wait > 0 {
+		uh.recordLogin(r, &u.ID, userInput.Email, entity.LoginLocked)
+		writeTooManyRequests(w, r, wait, "too many failed logins, try again later")
 		return
 	}
 
 	if !u.ValidatePassword(userInput.Password) {
+		if err := uh.failLogin(r, u); err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+		uh.recordLogin(r, &u.ID, userInput.Email, entity.LoginFailed)
 		time.Sleep(500 * time.Millisecond) // Delay to prevent timing attacks
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid credentials"})
+		problem.Write(w, r, problem.Unauthorized("invalid credentials"))
+		return
+	}
+
+	if u.FailedLogins > 0 {
+		if err := uh.UserDb.ResetFailedLogins(u.ID.String()); err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+	}
+
+	// Só informa o motivo depois que a senha foi confirmada
+	if err := u.CanLogin(); err != nil {
+		uh.recordLogin(r, &u.ID, userInput.Email, entity.LoginDenied)
+		problem.Write(w, r, err)
+		return
+	}
+
+	if uh.Verification.Required && !u.EmailVerified() {
+		uh.recordLogin(r, &u.ID, userInput.Email, entity.LoginDenied)
+		problem.Write(w, r, entity.ErrEmailNotVerified)
+		return
+	}
+
+	// Com MFA, a senha só dá direito ao desafio do segundo passo
+	if u.MFAEnabled {
+		uh.recordLogin(r, &u.ID, userInput.Email, entity.LoginMFARequired)
+		uh.writeMFAChallenge(w, r, u)
+		return
+	}
+
+	output, err := uh.issueTokens(u, entityPkg.NewID())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	uh.recordLogin(r, &u.ID, userInput.Email, entity.LoginSucceeded)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// RefreshJWT godoc
+// @Summary: Refresh a JWT token
+// @Description: Exchange a refresh token for a new access token and a rotated refresh token.
+// @Description: Reusing a refresh token that was already exchanged revokes every token of its family.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.RefreshJWTInput true "Refresh token"
+// @Success 200 {object} dto.GetJWTOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /auth/refresh [post]
+func (uh *UserHandler) RefreshJWT(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	defer r.Body.Close()
+
+	var input dto.RefreshJWTInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if strings.TrimSpace(input.RefreshToken) == "" {
+		problem.Write(w, r, problem.BadRequest("refresh_token is required"))
+		return
+	}
+
+	token, err := uh.RefreshTokenDB.FindRefreshTokenByHash(entityPkg.HashToken(input.RefreshToken))
+	if err != nil {
+		problem.Write(w, r, problem.Unauthorized("invalid refresh token"))
+		return
+	}
+
+	if err := token.Validate(); err != nil {
+		if errors.Is(err, entity.ErrRefreshTokenReused) {
+			// Um token já rotacionado foi reapresentado: revoga a família inteira
+			uh.RefreshTokenDB.RevokeRefreshTokenFamily(token.FamilyID.String())
+		}
+		problem.Write(w, r, problem.Unauthorized("invalid refresh token"))
+		return
+	}
+
+	marked, err := uh.RefreshTokenDB.MarkRefreshTokenUsed(token.ID.String())
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if !marked {
+		// Outra requisição consumiu o mesmo token ao mesmo tempo
+		uh.RefreshTokenDB.RevokeRefreshTokenFamily(token.FamilyID.String())
+		problem.Write(w, r, problem.Unauthorized("invalid refresh token"))
+		return
+	}
+
+	u, err := uh.UserDb.FindUserById(token.UserID.String())
+	if err != nil {
+		problem.Write(w, r, problem.Unauthorized("invalid refresh token"))
+		return
+	}
+
+	if err := u.CanLogin(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	output, err := uh.issueTokens(u, token.FamilyID)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Logout godoc
+// @Summary: Logout
+// @Description: Revoke the access token used in the request. When a refresh token is sent, its whole family is revoked too.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.LogoutInput false "Refresh token to revoke"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /auth/logout [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, problem.Unauthorized("invalid token"))
+		return
+	}
+	userID := principal.UserID
+
+	err := uh.TokenRevocationDB.RevokeToken(entity.NewRevokedToken(principal.TokenID, userID, principal.ExpiresAt))
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
 		return
 	}
 
+	// O corpo é opcional: só revoga o refresh token quando ele for enviado
+	var input dto.LogoutInput
+	if r.Body != nil {
+		defer r.Body.Close()
+		json.NewDecoder(r.Body).Decode(&input)
+	}
+
+	if input.RefreshToken != "" {
+		refreshToken, err := uh.RefreshTokenDB.FindRefreshTokenByHash(entityPkg.HashToken(input.RefreshToken))
+		if err == nil && refreshToken.UserID == userID {
+			uh.RefreshTokenDB.RevokeRefreshTokenFamily(refreshToken.FamilyID.String())
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "logged out successfully"})
+}
+
+// LogoutAll godoc
+// @Summary: Logout from all sessions
+// @Description: Revoke every access and refresh token issued to the authenticated user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /auth/logout-all [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, problem.Unauthorized("invalid token"))
+		return
+	}
+	userID := principal.UserID
+
+	err := uh.TokenRevocationDB.RevokeToken(entity.NewRevokedToken(principal.TokenID, userID, principal.ExpiresAt))
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if err := uh.revokeUserSessions(userID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "all sessions logged out successfully"})
+}
+
+func (uh *UserHandler) revokeUserSessions(userID string) error {
+	return revokeSessions(uh.TokenRevocationDB, uh.RefreshTokenDB, userID)
+}
+
+// revokeSessions invalidates every access token issued so far to the user
+// and revokes all of their refresh tokens.
+func revokeSessions(tokenRevocationDB database.TokenRevocationInterface, refreshTokenDB database.RefreshTokenInterface, userID string) error {
+	if err := tokenRevocationDB.RevokeUserTokens(userID, time.Now()); err != nil {
+		return err
+	}
+	return refreshTokenDB.RevokeUserRefreshTokens(userID)
+}
+
+// issueTokens generates a new access token for the user and a refresh token
+// that belongs to the given family.
+func (uh *UserHandler) issueTokens(u *entity.User, familyID entityPkg.ID) (*dto.GetJWTOutput, error) {
 	// Verifica se o JWT está configurado corretamente
 	if uh.Jwt == nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "JWT service not properly configured"})
-		return
+		return nil, errors.New("JWT service not properly configured")
 	}
 
 	// Verifica se o tempo de expiração é válido
-	if uh.JwtExpiresIn <= 0 {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid JWT expiration time"})
-		return
+	if uh.JwtExpiresIn <= 0 || uh.JwtRefreshExpiresIn <= 0 {
+		return nil, errors.New("invalid JWT expiration time")
+	}
+
+	now := time.Now()
+
+	// O papel e suas permissões vão no token, então mudanças nelas só valem
+	// para os tokens emitidos depois
+	permissions, err := uh.RoleDB.FindRolePermissions(u.RoleID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	principal := auth.Principal{
+		UserID:      u.ID,
+		Role:        u.Role.Name,
+		RoleID:      u.RoleID.String(),
+		Permissions: permissions,
+		TokenID:     entityPkg.NewID().String(),
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(time.Second * time.Duration(uh.JwtExpiresIn)),
+		// Com MFA ativo toda sessão passou pelo segundo fator, já que ativá-lo
+		// derruba as sessões abertas só com a senha
+		MFA: u.MFAEnabled,
+	}
+
+	accessToken, err := uh.encodeToken(principal.Claims())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, plain, err := entity.NewRefreshToken(u.ID, familyID, time.Second*time.Duration(uh.JwtRefreshExpiresIn))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate refresh token: %v", err)
 	}
 
-	// Cria o payload do token
-	claims := map[string]interface{}{
-		"sub":  u.ID.String(),
-		"exp":  time.Now().Add(time.Second * time.Duration(uh.JwtExpiresIn)).Unix(),
-		"role": u.RoleID,
+	if err := uh.RefreshTokenDB.CreateRefreshToken(refreshToken); err != nil {
+		return nil, fmt.Errorf("could not store refresh token: %v", err)
 	}
 
+	return &dto.GetJWTOutput{AccessToken: accessToken, RefreshToken: plain}, nil
+}
+
+// encodeToken signs the claims, giving up after a timeout.
+func (uh *UserHandler) encodeToken(claims map[string]interface{}) (string, error) {
 	// Gera o token com timeout
 	tokenChan := make(chan string, 1)
 	errChan := make(chan error, 1)
@@ -134,15 +405,11 @@ func (uh *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
 	// Aguarda a geração do token com timeout
 	select {
 	case tokenString := <-tokenChan:
-		accessToken := dto.GetJWTOutput{AccessToken: tokenString}
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(accessToken)
+		return tokenString, nil
 	case err := <-errChan:
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("could not generate token: %v", err)})
+		return "", fmt.Errorf("could not generate token: %v", err)
 	case <-time.After(5 * time.Second):
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "token generation timeout"})
+		return "", errors.New("token generation timeout")
 	}
 }
 
@@ -166,14 +433,15 @@ func (uh *UserHandler) TestAdmin(w http.ResponseWriter, r *http.Request) {
 
 // Create User
 // @Summary: Create a new user
-// @Description: Create a new user with the given name, email, and password
+// @Description: Create a new user with the given name, email, and password. The email starts unverified and a
+// @Description: verification link is sent to it.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body dto.CreateUserInput true "User data"
 // @Success 201 {object} dto.CreateUserInput
-// @Failure 400 {object} Error
-// @Failure 500 {object} Error
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /auth/register [post]
 func (uh *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var userInput dto.CreateUserInput
@@ -181,10 +449,19 @@ func (uh *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// Fechar o corpo da requisição após uso
 	defer r.Body.Close()
 
-	err := json.NewDecoder(r.Body).Decode(&userInput)
+	err := decodeJSON(r, &userInput)
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, userInput) {
+		return
+	}
+
+	if found, _ := uh.UserDb.FindUserByEmail(userInput.Email); found != nil {
+		problem.Write(w, r, problem.BadRequest("this email is already used"))
 		return
 	}
 
@@ -193,52 +470,66 @@ func (uh *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	roleCustomer, err := roleDB.FindRoleByName("customer")
 
 	if err != nil {
-		http.Error(w, `{"error": "could not find customer role"}`, http.StatusInternalServerError)
+		problem.Write(w, r, problem.Internal(err))
 		return
 	}
 
 	u, err := entity.NewUser(userInput.Name, userInput.Email, userInput.Password, roleCustomer.ID)
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
 	err = uh.UserDb.CreateUser(u)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"message": "user created successfully"})
+	// A conta já existe, então uma falha no email não desfaz o cadastro
+	if err := uh.sendVerificationEmail(u); err != nil {
+		log.Printf("Erro ao gerar o link de verificação de email: %v\n", err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "user created successfully, check your email to verify it"})
 
 }
 
 // GetUserById godoc
 // @Summary: Get a user by ID
-// @Description: Retrieve user information by ID
+// @Description: Retrieve user information by ID. Users can only get their own account; admins use /admin/user/{id}.
 // @Tags user
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} entity.User
-// @Failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /user/{id} [get]
 // @Security ApiKeyAuth
 func (uh *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if id == "" {
-		http.Error(w, `{"error": "missing user ID"}`, http.StatusBadRequest)
+		problem.Write(w, r, problem.BadRequest("missing user ID"))
 		return
 	}
 
-	user, err := uh.UserDb.FindUserById(id)
+	// A conta traz o estado de login e de segurança, então cada um só vê a
+	// sua. A checagem vem antes da busca para não revelar se o ID existe
+	userID, err := entityPkg.ParseID(id)
+	if actorID := actorFromClaims(r); err != nil || actorID == nil || *actorID != userID {
+		problem.Write(w, r, problem.Forbidden("you can only get your own account"))
+		return
+	}
+
+	user, err := uh.UserDb.FindUserById(userID.String())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"error": "user not found"}`, http.StatusNotFound)
+			problem.Write(w, r, problem.NotFound("user not found"))
 		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+			problem.Write(w, r, problem.Internal(err))
 		}
 		return
 	}
@@ -257,102 +548,92 @@ func (uh *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param profile body dto.UpdateOwnProfileInput true "Profile data"
 // @Success 200 {object} dto.UpdateOwnProfileInput
-// @Failure 400 {object} Error
-// @Failure 403 {object} Error
-// @Failure 500 {object} Error
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /user/profile [put]
 // @Security ApiKeyAuth
 func (uh *UserHandler) UpdateOwnProfile(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close() // Garante que qualquer recurso seja fechado
 
 	var userInput dto.UpdateOwnProfileInput
-	err := json.NewDecoder(r.Body).Decode(&userInput)
+	err := decodeJSON(r, &userInput)
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
 
 	// Valida os campos obrigatórios
-	if err := validate.Struct(userInput); err != nil {
-		validationErrors := err.(validator.ValidationErrors)
-		errorsMap := make(map[string]string)
-
-		for _, fieldErr := range validationErrors {
-			errorsMap[fieldErr.Field()] = fmt.Sprintf("Field '%s' is required and must be valid", fieldErr.Field())
-		}
-
-		response, _ := json.Marshal(map[string]interface{}{"errors": errorsMap})
-		http.Error(w, string(response), http.StatusBadRequest)
+	if !validateInput(w, r, userInput) {
 		return
 	}
 
-	_, claims, _ := jwtauth.FromContext(r.Context())
-
-	userId, ok := claims["sub"].(string)
-	if !ok || userId == "" {
-		http.Error(w, `{"error": "invalid token: missing 'sub'"}`, http.StatusForbidden)
-		return
-	}
-
-	userRole, ok := claims["role"].(string)
-	if !ok || userRole == "" {
-		userRole = "customer"
-		// http.Error(w, `{"error": "invalid token: missing 'role'"}`, http.StatusForbidden)
-		// return
-	}
-
-	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusInternalServerError)
-		return
-	}
-
-	if userInput.NewPassword == "" {
-		userInput.NewPassword = userInput.Password
-	}
-
-	hashedPassword, err := entityPkg.HashPassword(userInput.NewPassword)
-
-	if err != nil {
-		http.Error(w, `{"error": "failed to hash password"}`, http.StatusInternalServerError)
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
 		return
 	}
+	userId := principal.UserID.String()
 
 	foundedUser, err := uh.UserDb.FindUserById(userId)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"error": "user not found"}`, http.StatusNotFound)
+			problem.Write(w, r, problem.NotFound("user not found"))
 		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+			problem.Write(w, r, problem.Internal(err))
 		}
 		return
 	}
 
 	if err != nil || !foundedUser.ValidatePassword(userInput.Password) {
 		time.Sleep(500 * time.Millisecond) // Pequeno delay para evitar timing attacks
-		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthorized("Unauthorized"))
 		return
 	}
 
 	foundedUserByEmail, _ := uh.UserDb.FindUserByEmail(userInput.Email)
 	if foundedUserByEmail != nil {
 		if foundedUserByEmail.ID != foundedUser.ID {
-			http.Error(w, `{"error": "this email is already used"}`, http.StatusBadRequest)
+			problem.Write(w, r, problem.BadRequest("this email is already used"))
 			return
 		}
 	}
 
+	emailChanged := foundedUser.Email != userInput.Email
 	foundedUser.Name = userInput.Name
-	foundedUser.Email = userInput.Email
-	foundedUser.Password = hashedPassword
-	// foundedUser.Role = userRole
+	foundedUser.ChangeEmail(userInput.Email)
+	if userInput.NewPassword != "" && userInput.NewPassword != userInput.Password {
+		if err := foundedUser.ChangePassword(userInput.NewPassword); err != nil {
+			if errors.Is(err, entity.ErrPasswordTooShort) {
+				err = problem.Validation(err.Error(), problem.FieldError{Field: "new_password", Message: err.Error()})
+			}
+			problem.Write(w, r, err)
+			return
+		}
+	}
 
 	err = uh.UserDb.UpdateUser(foundedUser)
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": %s}`, err.Error()), http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
+	// Um email novo precisa ser verificado de novo
+	if emailChanged {
+		if err := uh.sendVerificationEmail(foundedUser); err != nil {
+			log.Printf("Erro ao gerar o link de verificação de email: %v\n", err)
+		}
+	}
+
+	// Trocar a senha derruba as sessões abertas com a senha antiga
+	if userInput.NewPassword != userInput.Password {
+		if err := uh.revokeUserSessions(foundedUser.ID.String()); err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(userInput)
@@ -365,34 +646,301 @@ func (uh *UserHandler) UpdateOwnProfile(w http.ResponseWriter, r *http.Request)
 // @Tags user
 // @Produce json
 // @Success 200 {object} entity.User
-// @Failure 403 {object} Error
-// @Failure 404 {object} Error
-// @Failure 500 {object} Error
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /user/profile [get]
 // @Security ApiKeyAuth
 func (uh *UserHandler) ShowOwnProfile(w http.ResponseWriter, r *http.Request) {
-	_, claims, _ := jwtauth.FromContext(r.Context())
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Write(w, r, problem.Forbidden("invalid token"))
+		return
+	}
 
-	userId, ok := claims["sub"].(string)
+	userFound, err := uh.UserDb.FindUserById(principal.UserID.String())
 
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("user not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(userFound)
+
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List users ordered by name, optionally searching by name or email and filtering by role or status
+// @Tags admin users
+// @Produce json
+// @Param q query string false "Part of the name or email, case insensitive"
+// @Param role_id query string false "Role ID"
+// @Param disabled query bool false "Only disabled (true) or enabled (false) users"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} dto.UserListOutput
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user [get]
+// @Security ApiKeyAuth
+func (uh *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	page, limit := parsePage(r, defaultPageLimit)
+	params := r.URL.Query()
+
+	query := database.UserQuery{
+		Page:   page,
+		Limit:  limit,
+		Search: strings.TrimSpace(params.Get("q")),
+		RoleID: params.Get("role_id"),
+	}
+
+	if disabled := params.Get("disabled"); disabled != "" {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			problem.Write(w, r, invalidParam("disabled", "must be true or false"))
+			return
+		}
+		query.Disabled = &value
+	}
+
+	users, total, err := uh.UserDb.FindUsers(query)
+	if err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	setPageLinks(w, r, page, limit, total)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.UserListOutput{
+		Items:   users,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		HasNext: int64(page*limit) < total,
+	})
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Description Retrieve any user by ID, with their login and security state
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.User
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id} [get]
+// @Security ApiKeyAuth
+func (uh *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadUser(w, r, chi.URLParam(r, "id"))
 	if !ok {
-		http.Error(w, `{"error": "invalid token"}`, http.StatusForbidden)
 		return
 	}
 
-	userFound, err := uh.UserDb.FindUserById(userId)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
 
+// UpdateUserRole godoc
+// @Summary Change the role of a user
+// @Description Move the user to another role. The open sessions of the user are logged out.
+// @Tags admin users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role body dto.UpdateUserRoleInput true "New role"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id}/role [put]
+// @Security ApiKeyAuth
+func (uh *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.UpdateUserRoleInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	user, ok := uh.loadManagedUser(w, r, "change the role of")
+	if !ok {
+		return
+	}
+
+	role, err := uh.RoleDB.FindRoleByID(input.RoleID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, `{"error": "user not found"}`, http.StatusNotFound)
+			problem.Write(w, r, problem.Validation("role not found", problem.FieldError{Field: "role_id", Message: "does not exist"}))
 		} else {
-			http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return
+	}
+
+	user.RoleID = role.ID
+	user.Role = *role
+	uh.saveManagedUser(w, r, user, true, "role_id")
+}
+
+// DisableUser godoc
+// @Summary Disable a user
+// @Description Block the login of the user and log out their open sessions
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.User
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id}/disable [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadManagedUser(w, r, "disable")
+	if !ok {
+		return
+	}
+
+	user.Disabled = true
+	uh.saveManagedUser(w, r, user, true, "disabled")
+}
+
+// EnableUser godoc
+// @Summary Enable a user
+// @Description Allow a disabled user to log in again
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.User
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id}/enable [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadUser(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	user.Disabled = false
+	uh.saveManagedUser(w, r, user, false, "disabled")
+}
+
+// ForcePasswordReset godoc
+// @Summary Force a password reset
+// @Description Log out the open sessions of the user and block the login until the password is changed
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} entity.User
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id}/force-password-reset [post]
+// @Security ApiKeyAuth
+func (uh *UserHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadManagedUser(w, r, "force a password reset on")
+	if !ok {
+		return
+	}
+
+	user.PasswordResetRequired = true
+	uh.saveManagedUser(w, r, user, true, "password_reset_required")
+}
+
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description Delete the user with their cart and sessions. Their orders are kept.
+// @Tags admin users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/user/{id} [delete]
+// @Security ApiKeyAuth
+func (uh *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := uh.loadManagedUser(w, r, "delete")
+	if !ok {
+		return
+	}
+
+	// Derruba os access tokens antes, já que eles continuam válidos até expirar
+	if err := uh.revokeUserSessions(user.ID.String()); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if err := uh.UserDb.DeleteUser(user.ID.String()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = problem.NotFound("user not found")
 		}
+		problem.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(userFound)
+	json.NewEncoder(w).Encode(map[string]string{"message": "user deleted successfully"})
+}
+
+func (uh *UserHandler) loadUser(w http.ResponseWriter, r *http.Request, id string) (*entity.User, bool) {
+	user, err := uh.UserDb.FindUserById(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Write(w, r, problem.NotFound("user not found"))
+		} else {
+			problem.Write(w, r, problem.Internal(err))
+		}
+		return nil, false
+	}
+	return user, true
+}
 
+// loadManagedUser loads the user of the URL for an admin action that admins
+// can't take on their own account, so they don't lock themselves out.
+func (uh *UserHandler) loadManagedUser(w http.ResponseWriter, r *http.Request, action string) (*entity.User, bool) {
+	id := chi.URLParam(r, "id")
+	if actorID := actorFromClaims(r); actorID != nil && actorID.String() == id {
+		problem.Write(w, r, problem.Conflict(fmt.Sprintf("you cannot %s your own account", action)))
+		return nil, false
+	}
+	return uh.loadUser(w, r, id)
+}
+
+// saveManagedUser stores the columns an admin action changed and writes the
+// user back. With logout, the open sessions of the user are revoked, so the
+// change takes effect right away.
+func (uh *UserHandler) saveManagedUser(w http.ResponseWriter, r *http.Request, user *entity.User, logout bool, columns ...string) {
+	if err := uh.UserDb.UpdateUserColumns(user, columns...); err != nil {
+		problem.Write(w, r, problem.Internal(err))
+		return
+	}
+
+	if logout {
+		if err := uh.revokeUserSessions(user.ID.String()); err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
 }
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/dto"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/mailer"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/signer"
+)
+
+var errInvalidVerificationToken = problem.BadRequest("invalid or expired verification token")
+
+// EmailVerification configures how users prove they own their email.
+type EmailVerification struct {
+	// Signer signs the verification links, which aren't stored anywhere.
+	Signer *signer.Signer
+	Mailer mailer.Mailer
+	// URL is the page the links point to, with the token in the query.
+	URL       string
+	ExpiresIn int
+	// Required blocks the login until the email is verified.
+	Required bool
+}
+
+// verificationToken is the signed payload of a verification link. It names
+// the email, so the link stops working if the user changes it.
+type verificationToken struct {
+	UserID    string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// sendVerificationEmail emails a verification link to the user in the
+// background.
+func (uh *UserHandler) sendVerificationEmail(user *entity.User) error {
+	payload, err := json.Marshal(verificationToken{
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Duration(uh.Verification.ExpiresIn) * time.Second).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := linkWithToken(uh.Verification.URL, uh.Verification.Signer.Sign(payload))
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to verify your email. It expires in %d hours.\n\n%s\n\nIf you didn't create an account, you can ignore this email.\n",
+			user.Name, uh.Verification.ExpiresIn/3600, link),
+	}
+
+	go func() {
+		if err := uh.Verification.Mailer.Send(msg); err != nil {
+			log.Printf("Erro ao enviar o email de verificação para %s: %v\n", msg.To, err)
+		}
+	}()
+	return nil
+}
+
+// VerifyEmail godoc
+// @Summary Verify the email
+// @Description Verify the email of an account with the token of a verification link.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.VerifyEmailInput true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /auth/verify-email [post]
+func (uh *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input dto.VerifyEmailInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	payload, err := uh.Verification.Signer.Verify(input.Token)
+	if err != nil {
+		problem.Write(w, r, errInvalidVerificationToken)
+		return
+	}
+
+	var token verificationToken
+	if err := json.Unmarshal(payload, &token); err != nil || time.Now().Unix() > token.ExpiresAt {
+		problem.Write(w, r, errInvalidVerificationToken)
+		return
+	}
+
+	user, err := uh.UserDb.FindUserById(token.UserID)
+	if err != nil || user.Email != token.Email {
+		problem.Write(w, r, errInvalidVerificationToken)
+		return
+	}
+
+	// Usar o link de novo não muda nada
+	if !user.EmailVerified() {
+		user.VerifyEmail(time.Now())
+		if err := uh.UserDb.UpdateUser(user); err != nil {
+			problem.Write(w, r, problem.Internal(err))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "email verified successfully"})
+}
+
+// ResendVerification godoc
+// @Summary Resend the verification email
+// @Description Email a new verification link to an unverified account. The answer is the same whether the email is
+// @Description registered or not.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body dto.ResendVerificationInput true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 429 {object} problem.Problem
+// @Router /auth/resend-verification [post]
+func (uh *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	start := time.Now()
+
+	var input dto.ResendVerificationInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !validateInput(w, r, input) {
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if ok, retryAfter := uh.resendVerificationRate.Allow(email); !ok {
+		writeTooManyRequests(w, r, retryAfter, "too many verification requests for this email, try again later")
+		return
+	}
+
+	user, err := uh.UserDb.FindUserByEmail(input.Email)
+	if err == nil && !user.Disabled && !user.EmailVerified() {
+		if err := uh.sendVerificationEmail(user); err != nil {
+			log.Printf("Erro ao gerar o link de verificação de email: %v\n", err)
+		}
+	}
+
+	time.Sleep(time.Until(start.Add(mailRequestDelay)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the email is registered and unverified, a verification link was sent to it"})
+}
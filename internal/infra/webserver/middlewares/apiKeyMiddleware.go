@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+	"gorm.io/gorm"
+)
+
+// APIKeyVerifier authenticates requests sent with "Authorization: ApiKey
+// <key>". It runs after Verifier and puts in the context a token with the
+// claims of an access token of the user, plus the key ID and its scopes, so
+// Authenticator reads the same auth.Principal from it. Requests without an
+// API key are passed on untouched.
+//
+// The token is dated from the creation of the key, so logging out everywhere
+// also invalidates the keys created before.
+func APIKeyVerifier(apiKeyDB database.APIKeyInterface, userDB database.UserInterface, roleDB database.RoleInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain, ok := apiKeyFromHeader(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, user, err := authenticateAPIKey(apiKeyDB, userDB, plain)
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+
+			if err := apiKeyDB.TouchAPIKey(key, time.Now()); err != nil {
+				log.Printf("Erro ao registrar o uso da chave de API %s: %v\n", key.ID, err)
+			}
+
+			permissions, err := roleDB.FindRolePermissions(user.RoleID.String())
+			if err != nil {
+				problem.Write(w, r, problem.Internal(err))
+				return
+			}
+
+			token, err := apiKeyToken(key, user, permissions)
+			if err != nil {
+				problem.Write(w, r, problem.Internal(err))
+				return
+			}
+
+			ctx := jwtauth.NewContext(r.Context(), token, nil)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireSession rejects requests authenticated with an API key, for the
+// routes that manage the account itself.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.FromContext(r.Context()); ok && principal.IsAPIKey() {
+			problem.Write(w, r, problem.Forbidden("API keys can't be used here, log in instead"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func apiKeyFromHeader(r *http.Request) (string, bool) {
+	scheme, key, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "ApiKey") {
+		return "", false
+	}
+	return strings.TrimSpace(key), true
+}
+
+func authenticateAPIKey(apiKeyDB database.APIKeyInterface, userDB database.UserInterface, plain string) (*entity.APIKey, *entity.User, error) {
+	hash, err := entity.HashAPIKey(plain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := apiKeyDB.FindAPIKeyByHash(hash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, entity.ErrInvalidAPIKey
+		}
+		return nil, nil, problem.Internal(err)
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := userDB.FindUserById(key.UserID.String())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, entity.ErrInvalidAPIKey
+		}
+		return nil, nil, problem.Internal(err)
+	}
+
+	if err := user.CanLogin(); err != nil {
+		return nil, nil, err
+	}
+
+	return key, user, nil
+}
+
+func apiKeyToken(key *entity.APIKey, user *entity.User, permissions []string) (jwt.Token, error) {
+	principal := auth.Principal{
+		UserID:      user.ID,
+		Role:        user.Role.Name,
+		RoleID:      user.RoleID.String(),
+		Permissions: permissions,
+		TokenID:     key.ID.String(),
+		IssuedAt:    key.CreatedAt,
+		MFA:         user.MFAEnabled,
+		APIKeyID:    key.ID.String(),
+		Scopes:      key.ScopeNames(),
+	}
+
+	token := jwt.New()
+	for name, value := range principal.Claims() {
+		if err := token.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
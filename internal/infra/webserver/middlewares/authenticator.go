@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+)
+
+// Authenticator works like jwtauth.Authenticator, rejecting requests without
+// a valid token, but answers with a problem+json body. It must run after
+// Verifier, which already checks the signature and expiration. The claims
+// of the token are read into an auth.Principal, which the next handlers get
+// with auth.FromContext.
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+		if err != nil || token == nil {
+			problem.Write(w, r, problem.Unauthorized("invalid or missing token"))
+			return
+		}
+
+		principal, err := auth.FromToken(token)
+		if err != nil {
+			problem.Write(w, r, problem.Unauthorized("invalid token"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	})
+}
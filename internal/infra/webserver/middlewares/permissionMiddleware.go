@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+)
+
+// RequirePermission lets the request through only when the token was issued
+// with the permission, an API key with scopes has it among them and, if the
+// role requires MFA, the token was issued after the second factor. The
+// permissions come from the token, so changes to a role reach its users when
+// they refresh their tokens. Pass a database.RolePermissionCache as roleDB
+// so that the MFA requirement isn't read from the database on every request.
+// It must run after Authenticator.
+func RequirePermission(roleDB database.RoleInterface, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok || principal.RoleID == "" {
+				problem.Write(w, r, problem.Forbidden("invalid token"))
+				return
+			}
+
+			if !slices.Contains(principal.Permissions, permission) {
+				problem.Write(w, r, problem.Forbidden("missing permission "+permission))
+				return
+			}
+
+			// Chaves de API com escopos só usam as permissões escolhidas
+			if !principal.Can(permission) {
+				problem.Write(w, r, problem.Forbidden("API key is missing scope "+permission))
+				return
+			}
+
+			// O token só conta como MFA se foi emitido depois do segundo fator
+			requireMFA, err := roleDB.RoleRequiresMFA(principal.RoleID)
+			if err != nil {
+				problem.Write(w, r, problem.Internal(err))
+				return
+			}
+			if requireMFA && !principal.MFA {
+				problem.Write(w, r, problem.Forbidden("your role requires two-factor authentication: set it up at /user/mfa/totp/setup and log in again"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,46 +1,31 @@
 package middlewares
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
+	"slices"
 
-	"github.com/go-chi/jwtauth"
-	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
 )
 
-func RoleMiddleware(roleDB database.RoleInterface, allowedRoles ...string) func(http.Handler) http.Handler {
+// RoleMiddleware lets the request through only when the role of the token is
+// one of the allowed ones, by name. Prefer RequirePermission, which doesn't
+// tie the routes to role names. It must run after Authenticator.
+func RoleMiddleware(allowedRoles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			_, claims, _ := jwtauth.FromContext(r.Context())
-
-			userRole, ok := claims["role"].(string)
+			principal, ok := auth.FromContext(r.Context())
 			if !ok {
-				claimsJSON, _ := json.Marshal(claims) // Converte claims para JSON
-				http.Error(w, fmt.Sprintf(`{"error": "invalid token", "claims": %s}`, claimsJSON), http.StatusForbidden)
+				problem.Write(w, r, problem.Forbidden("invalid token"))
 				return
 			}
 
-			// Primeiro tenta comparar diretamente o ID com o nome permitido
-			for _, role := range allowedRoles {
-				if userRole == role {
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			// Caso não seja um nome, tenta buscar o nome da role pelo ID
-			roleEntity, err := roleDB.FindRoleByID(userRole)
-			if err == nil {
-				for _, role := range allowedRoles {
-					if roleEntity.Name == role {
-						next.ServeHTTP(w, r)
-						return
-					}
-				}
+			if !slices.Contains(allowedRoles, principal.Role) {
+				problem.Write(w, r, problem.Forbidden("forbidden"))
+				return
 			}
 
-			http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
+			next.ServeHTTP(w, r)
 		})
 	}
 }
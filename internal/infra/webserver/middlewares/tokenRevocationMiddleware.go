@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/database"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/auth"
+	"github.com/mateusfaustino/go-rest-api-III/internal/infra/webserver/problem"
+)
+
+// TokenRevocationMiddleware rejects tokens that were revoked through logout
+// or issued before the user's "revoked before" cut-off. It must run after
+// Authenticator; requests without a principal are left alone.
+func TokenRevocationMiddleware(revocationDB database.TokenRevocationInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			revoked, err := revocationDB.IsTokenRevoked(principal.TokenID)
+			if err != nil {
+				problem.Write(w, r, problem.Internal(err))
+				return
+			}
+
+			if revoked {
+				problem.Write(w, r, problem.Unauthorized("token revoked"))
+				return
+			}
+
+			before, err := revocationDB.FindUserTokensRevokedBefore(principal.UserID.String())
+			if err != nil {
+				problem.Write(w, r, problem.Internal(err))
+				return
+			}
+
+			// O iat tem precisão de segundos, então compara na mesma unidade
+			if !before.IsZero() && principal.IssuedAt.Unix() < before.Unix() {
+				problem.Write(w, r, problem.Unauthorized("token revoked"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
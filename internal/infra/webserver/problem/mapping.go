@@ -0,0 +1,73 @@
+package problem
+
+import (
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/cursor"
+	entityPkg "github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+	"github.com/mateusfaustino/go-rest-api-III/pkg/patch"
+	"gorm.io/gorm"
+)
+
+// mapping links a domain error to its kind and, for validation errors, to
+// the request field it is about.
+type mapping struct {
+	err   error
+	kind  Kind
+	field string
+}
+
+var mappings = []mapping{
+	{err: gorm.ErrRecordNotFound, kind: KindNotFound},
+	{err: entity.ErrCartItemNotFound, kind: KindNotFound},
+
+	{err: entity.ErrIDIsRequired, kind: KindValidation, field: "id"},
+	{err: entity.ErrInvalidID, kind: KindValidation, field: "id"},
+	{err: entity.ErrNameIsRequired, kind: KindValidation, field: "name"},
+	{err: entity.ErrPriceIsRequired, kind: KindValidation, field: "price"},
+	{err: entity.ErrInvalidPrice, kind: KindValidation, field: "price"},
+	{err: entity.ErrInvalidLowStock, kind: KindValidation, field: "low_stock_threshold"},
+	{err: entity.ErrCategoryCycle, kind: KindValidation, field: "parent_id"},
+	{err: entity.ErrInvalidMovementType, kind: KindValidation, field: "type"},
+	{err: entity.ErrInvalidQuantity, kind: KindValidation, field: "quantity"},
+	{err: entity.ErrReasonIsRequired, kind: KindValidation, field: "reason"},
+	{err: entity.ErrOrderIsEmpty, kind: KindValidation, field: "items"},
+	{err: entity.ErrInvalidOrderStatus, kind: KindValidation, field: "status"},
+	{err: entity.ErrInvalidMFACode, kind: KindValidation, field: "code"},
+	{err: entity.ErrPasswordTooShort, kind: KindValidation, field: "password"},
+	{err: entity.ErrAPIKeyExpiresInPast, kind: KindValidation, field: "expires_at"},
+	{err: entity.ErrAPIKeyScopeNotGranted, kind: KindValidation, field: "scopes"},
+	{err: entityPkg.ErrInvalidAmount, kind: KindValidation},
+	{err: entityPkg.ErrInvalidCurrency, kind: KindValidation},
+	{err: entityPkg.ErrCurrencyMismatch, kind: KindValidation},
+
+	{err: cursor.ErrInvalidCursor, kind: KindBadRequest},
+	{err: patch.ErrInvalidPatch, kind: KindBadRequest},
+
+	{err: entity.ErrRefreshTokenExpired, kind: KindUnauthorized},
+	{err: entity.ErrRefreshTokenRevoked, kind: KindUnauthorized},
+	{err: entity.ErrRefreshTokenReused, kind: KindUnauthorized},
+	{err: entity.ErrInvalidAPIKey, kind: KindUnauthorized},
+	{err: entity.ErrAPIKeyExpired, kind: KindUnauthorized},
+	{err: entity.ErrAPIKeyRevoked, kind: KindUnauthorized},
+
+	{err: entity.ErrUserDisabled, kind: KindForbidden},
+	{err: entity.ErrPasswordResetRequired, kind: KindForbidden},
+	{err: entity.ErrEmailNotVerified, kind: KindForbidden},
+
+	{err: entity.ErrInsufficientStock, kind: KindConflict},
+	{err: entity.ErrInvalidTransition, kind: KindConflict},
+	{err: entity.ErrOrderStatusConflict, kind: KindConflict},
+	{err: entity.ErrCartChanged, kind: KindConflict},
+	{err: entity.ErrBuiltInRole, kind: KindConflict},
+	{err: entity.ErrAdminRoleLocked, kind: KindConflict},
+	{err: entity.ErrRoleInUse, kind: KindConflict},
+	{err: entity.ErrRoleNameIsTaken, kind: KindConflict},
+	{err: entity.ErrMFAAlreadyEnabled, kind: KindConflict},
+	{err: entity.ErrMFANotEnabled, kind: KindConflict},
+	{err: entity.ErrMFASetupNotStarted, kind: KindConflict},
+	{err: entity.ErrMFARequiredByRole, kind: KindConflict},
+	{err: patch.ErrPathNotFound, kind: KindConflict},
+	{err: patch.ErrTestFailed, kind: KindConflict},
+
+	{err: entity.ErrProductVersionConflict, kind: KindPreconditionFailed},
+}
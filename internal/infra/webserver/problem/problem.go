@@ -0,0 +1,200 @@
+// Package problem writes API errors as RFC 7807 problem details
+// (application/problem+json). Handlers return typed errors built with the
+// constructors of this package, or plain domain errors, and Write maps them
+// to the right status code.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// Kind classifies an error and decides its status code and problem type.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindPreconditionFailed
+	KindUnsupportedMediaType
+	KindTooManyRequests
+)
+
+var kindStatus = map[Kind]int{
+	KindInternal:             http.StatusInternalServerError,
+	KindBadRequest:           http.StatusBadRequest,
+	KindValidation:           http.StatusBadRequest,
+	KindUnauthorized:         http.StatusUnauthorized,
+	KindForbidden:            http.StatusForbidden,
+	KindNotFound:             http.StatusNotFound,
+	KindConflict:             http.StatusConflict,
+	KindPreconditionFailed:   http.StatusPreconditionFailed,
+	KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	KindTooManyRequests:      http.StatusTooManyRequests,
+}
+
+var kindType = map[Kind]string{
+	KindInternal:             "/problems/internal-error",
+	KindBadRequest:           "/problems/bad-request",
+	KindValidation:           "/problems/validation-error",
+	KindUnauthorized:         "/problems/unauthorized",
+	KindForbidden:            "/problems/forbidden",
+	KindNotFound:             "/problems/not-found",
+	KindConflict:             "/problems/conflict",
+	KindPreconditionFailed:   "/problems/precondition-failed",
+	KindUnsupportedMediaType: "/problems/unsupported-media-type",
+	KindTooManyRequests:      "/problems/too-many-requests",
+}
+
+// Status returns the HTTP status code of the kind.
+func (k Kind) Status() int {
+	if status, ok := kindStatus[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// FieldError describes why a single field of the request is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error that knows how it should be shown to the client. Err,
+// when set, is the cause; it is logged but never sent for internal errors.
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func BadRequest(message string) *Error {
+	return &Error{Kind: KindBadRequest, Message: message}
+}
+
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+func UnsupportedMediaType(message string) *Error {
+	return &Error{Kind: KindUnsupportedMediaType, Message: message}
+}
+
+func TooManyRequests(message string) *Error {
+	return &Error{Kind: KindTooManyRequests, Message: message}
+}
+
+// Internal wraps an unexpected error. Its message is not shown to the client.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Err: err}
+}
+
+// Problem is the RFC 7807 body of an error response.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// From classifies any error. Typed errors are kept, known domain errors are
+// mapped through the table in mapping.go and everything else is internal.
+func From(err error) *Error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			e := &Error{Kind: m.kind, Message: err.Error(), Err: err}
+			if m.field != "" {
+				e.Fields = []FieldError{{Field: m.field, Message: err.Error()}}
+			}
+			return e
+		}
+	}
+
+	return Internal(err)
+}
+
+// New builds the problem body of the error for the request.
+func New(r *http.Request, err error) Problem {
+	e := From(err)
+	status := e.Kind.Status()
+
+	p := Problem{
+		Type:   kindType[e.Kind],
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: e.Message,
+		Errors: e.Fields,
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+
+	if e.Kind == KindInternal {
+		// O detalhe de erros internos fica só no log
+		p.Detail = "internal server error"
+	}
+	return p
+}
+
+// Write sends the error as a problem+json response. Internal errors are
+// logged with the request they came from.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := New(r, err)
+
+	if p.Status >= http.StatusInternalServerError && r != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,70 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mateusfaustino/go-rest-api-III/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func write(t *testing.T, err error) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/product/123", nil)
+	w := httptest.NewRecorder()
+	Write(w, r, err)
+
+	var p Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return w, p
+}
+
+func TestWriteTypedError(t *testing.T) {
+	w, p := write(t, NotFound("product not found"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "/problems/not-found", p.Type)
+	assert.Equal(t, "Not Found", p.Title)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "product not found", p.Detail)
+	assert.Equal(t, "/product/123", p.Instance)
+}
+
+func TestWriteMapsDomainErrors(t *testing.T) {
+	w, p := write(t, fmt.Errorf("find product: %w", gorm.ErrRecordNotFound))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+
+	w, p = write(t, entity.ErrInvalidPrice)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "/problems/validation-error", p.Type)
+	assert.Equal(t, []FieldError{{Field: "price", Message: "Invalid price"}}, p.Errors)
+
+	w, _ = write(t, entity.ErrInsufficientStock)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestWriteHidesInternalErrors(t *testing.T) {
+	w, p := write(t, errors.New("dial tcp 10.0.0.1:3306: connection refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "internal server error", p.Detail)
+	assert.NotContains(t, w.Body.String(), "10.0.0.1")
+}
+
+func TestValidationFields(t *testing.T) {
+	w, p := write(t, Validation("invalid input",
+		FieldError{Field: "quantity", Message: "must be at least 1"},
+		FieldError{Field: "product_id", Message: "is required"},
+	))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, p.Errors, 2)
+	assert.Equal(t, "quantity", p.Errors[0].Field)
+}
@@ -0,0 +1,49 @@
+// Package cursor implements opaque keyset pagination cursors for lists
+// ordered by creation date and id.
+package cursor
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/signer"
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// Cursor is the position right after the last item of a page: the next page
+// starts after (CreatedAt, ID) in the direction given by Desc.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Desc      bool      `json:"d,omitempty"`
+}
+
+// Codec turns cursors into signed opaque strings and back, so clients can't
+// craft positions themselves.
+type Codec struct {
+	signer *signer.Signer
+}
+
+func NewCodec(s *signer.Signer) *Codec {
+	return &Codec{signer: s}
+}
+
+func (c *Codec) Encode(cur Cursor) string {
+	payload, _ := json.Marshal(cur)
+	return c.signer.Sign(payload)
+}
+
+func (c *Codec) Decode(token string) (*Cursor, error) {
+	payload, err := c.signer.Verify(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cur Cursor
+	if err := json.Unmarshal(payload, &cur); err != nil || cur.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cur, nil
+}
@@ -0,0 +1,25 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeAndDecode(t *testing.T) {
+	codec := NewCodec(signer.New([]byte("secret"), "cursor"))
+	created := time.Date(2026, 10, 18, 12, 30, 0, 123456789, time.FixedZone("BRT", -3*3600))
+
+	token := codec.Encode(Cursor{CreatedAt: created, ID: "abc", Desc: true})
+	cur, err := codec.Decode(token)
+	assert.NoError(t, err)
+	assert.True(t, created.Equal(cur.CreatedAt))
+	assert.Equal(t, "abc", cur.ID)
+	assert.True(t, cur.Desc)
+
+	other := NewCodec(signer.New([]byte("other"), "cursor"))
+	_, err = other.Decode(token)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
@@ -0,0 +1,275 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("Invalid amount")
+	ErrInvalidCurrency  = errors.New("Invalid currency")
+	ErrCurrencyMismatch = errors.New("Currency mismatch")
+)
+
+// DefaultCurrency is used when an amount is informed without a currency.
+const DefaultCurrency = "BRL"
+
+// currencyExponents holds the number of minor unit digits of the ISO 4217
+// currencies we accept.
+var currencyExponents = map[string]int{
+	"ARS": 2,
+	"AUD": 2,
+	"BHD": 3,
+	"BRL": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CLP": 0,
+	"CNY": 2,
+	"COP": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"MXN": 2,
+	"PYG": 0,
+	"USD": 2,
+	"UYU": 2,
+}
+
+var amountPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// Money is an exact amount of a currency, kept as an integer number of minor
+// units (cents for BRL). It is stored as two columns, so it should be
+// embedded with a prefix: `gorm:"embedded;embeddedPrefix:price_"`.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"column:amount;not null;default:0" swaggertype:"string" example:"12.90"`
+	Currency string `json:"currency" gorm:"column:currency;type:char(3)" example:"BRL"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney reads a decimal amount like "1234.56" in the given currency.
+// Digits beyond the currency minor unit are rounded half to even, so
+// "0.125" BRL becomes 0.12 and "0.135" becomes 0.14.
+func ParseMoney(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, ErrInvalidCurrency
+	}
+
+	amount = strings.TrimSpace(amount)
+	if !amountPattern.MatchString(amount) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return Money{}, ErrInvalidAmount
+	}
+	value.Mul(value, new(big.Rat).SetInt(pow10(exponent)))
+
+	minor := roundHalfEven(value)
+	if !minor.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	return Money{Amount: minor.Int64(), Currency: currency}, nil
+}
+
+// ParseMoneyText reads an amount optionally followed by its currency, like
+// "1234.50" or "1234.50 USD". Without a currency the amount is read in
+// DefaultCurrency.
+func ParseMoneyText(text string) (Money, error) {
+	parts := strings.Fields(text)
+	switch len(parts) {
+	case 1:
+		return ParseMoney(parts[0], DefaultCurrency)
+	case 2:
+		return ParseMoney(parts[0], parts[1])
+	default:
+		return Money{}, ErrInvalidAmount
+	}
+}
+
+// MustParseMoney is like ParseMoney but panics on invalid input. It is meant
+// for constants and tests.
+func MustParseMoney(amount, currency string) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(fmt.Sprintf("money: %q %s: %v", amount, currency, err))
+	}
+	return m
+}
+
+// Exponent returns the number of minor unit digits of the currency.
+func (m Money) Exponent() int {
+	return currencyExponents[m.Currency]
+}
+
+// Validate checks that the currency is a known ISO 4217 code.
+func (m Money) Validate() error {
+	if _, ok := currencyExponents[m.Currency]; !ok {
+		return ErrInvalidCurrency
+	}
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// Add sums two amounts of the same currency. The zero Money, without a
+// currency, is accepted on either side so totals can start from it.
+func (m Money) Add(other Money) (Money, error) {
+	switch {
+	case m.Currency == "" && m.Amount == 0:
+		return other, nil
+	case other.Currency == "" && other.Amount == 0:
+		return m, nil
+	case m.Currency != other.Currency:
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul multiplies the amount by a quantity. It is exact, no rounding needed.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// String formats the amount with the currency minor unit digits, e.g.
+// "1234.50".
+func (m Money) String() string {
+	exponent := m.Exponent()
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, amount)
+	if exponent == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON writes the amount as a decimal string, so clients never parse
+// it as a float: {"amount": "1234.50", "currency": "BRL"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+// UnmarshalJSON accepts the structured form written by MarshalJSON (with the
+// amount as a string or a number), a bare decimal string like "1234.50" or
+// "1234.50 USD", or a bare number. Amounts without a currency are read in
+// DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var amount, currency string
+
+	switch data[0] {
+	case '{':
+		var raw moneyJSON
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		value, err := rawAmount(raw.Amount)
+		if err != nil {
+			return err
+		}
+		amount, currency = value, raw.Currency
+	case '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		parsed, err := ParseMoneyText(text)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	default:
+		amount = string(data)
+	}
+
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	parsed, err := ParseMoney(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func rawAmount(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", ErrInvalidAmount
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return "", err
+		}
+		return text, nil
+	}
+	return string(raw), nil
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+func roundHalfEven(value *big.Rat) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// Compara o dobro do resto com o denominador para saber se passou da metade
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	cmp := twice.Cmp(value.Denom())
+
+	if cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1) {
+		if value.Sign() < 0 {
+			return quotient.Sub(quotient, big.NewInt(1))
+		}
+		return quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	m, err := ParseMoney("1234.5", "brl")
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(123450, "BRL"), m)
+	assert.Equal(t, "1234.50", m.String())
+
+	m, err = ParseMoney("-0.07", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-7), m.Amount)
+	assert.Equal(t, "-0.07", m.String())
+
+	m, err = ParseMoney("1500", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, "1500", m.String())
+
+	_, err = ParseMoney("1e3", "BRL")
+	assert.Equal(t, ErrInvalidAmount, err)
+
+	_, err = ParseMoney("10", "XXX")
+	assert.Equal(t, ErrInvalidCurrency, err)
+}
+
+func TestParseMoneyRoundsHalfToEven(t *testing.T) {
+	cases := map[string]int64{
+		"0.125":  12,
+		"0.135":  14,
+		"0.1251": 13,
+		"-0.125": -12,
+		"-0.135": -14,
+		"9.999":  1000,
+	}
+	for amount, expected := range cases {
+		m, err := ParseMoney(amount, "BRL")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, m.Amount, amount)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	price := MustParseMoney("0.10", "BRL")
+
+	total := Money{}
+	for i := 0; i < 3; i++ {
+		var err error
+		total, err = total.Add(price)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "0.30", total.String())
+	assert.True(t, total.Equal(price.Mul(3)))
+
+	_, err := total.Add(MustParseMoney("1", "USD"))
+	assert.Equal(t, ErrCurrencyMismatch, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(MustParseMoney("19.9", "BRL"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": "19.90", "currency": "BRL"}`, string(data))
+
+	inputs := map[string]Money{
+		`{"amount": "19.90", "currency": "USD"}`: NewMoney(1990, "USD"),
+		`{"amount": 19.9}`:                       NewMoney(1990, DefaultCurrency),
+		`"19.90 EUR"`:                            NewMoney(1990, "EUR"),
+		`"19.9"`:                                 NewMoney(1990, DefaultCurrency),
+		`19.9`:                                   NewMoney(1990, DefaultCurrency),
+	}
+	for input, expected := range inputs {
+		var m Money
+		assert.NoError(t, json.Unmarshal([]byte(input), &m), input)
+		assert.Equal(t, expected, m, input)
+	}
+
+	var m Money
+	assert.Error(t, json.Unmarshal([]byte(`"abc"`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "1", "currency": "XXX"}`), &m))
+}
@@ -0,0 +1,263 @@
+// Package jwtkeys signs and verifies JWTs with a set of keys told apart by
+// the kid header. One key signs new tokens; the others only verify, so a key
+// can be rotated while the tokens it signed are still valid. The public keys
+// are published as a JWKS, letting other services verify the tokens without
+// any secret.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var (
+	ErrInvalidPEM        = errors.New("Invalid PEM key")
+	ErrUnsupportedKey    = errors.New("Unsupported key type")
+	ErrUnknownKeyID      = errors.New("Unknown key ID")
+	ErrAlgorithmMismatch = errors.New("Token algorithm doesn't match its key")
+	ErrNotSigningKey     = errors.New("Key can't sign")
+	ErrEmptySecret       = errors.New("Empty secret")
+)
+
+// Key is a signing or verification key with its ID and algorithm.
+type Key struct {
+	ID        string
+	Algorithm jwa.SignatureAlgorithm
+
+	// signer holds the private or symmetric key, nil for public keys.
+	signer jwk.Key
+	public interface{}
+}
+
+// NewSymmetricKey returns an HS256 key. Symmetric keys are never published.
+func NewSymmetricKey(id string, secret []byte) (*Key, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+	signer, err := jwk.New(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := signer.Set(jwk.KeyIDKey, id); err != nil {
+		return nil, err
+	}
+	return &Key{ID: id, Algorithm: jwa.HS256, signer: signer, public: secret}, nil
+}
+
+// ParsePrivateKeyPEM reads an RSA, P-256 or Ed25519 private key in PKCS#8,
+// PKCS#1 or SEC 1 form. The algorithm follows from the key type: RS256,
+// ES256 or EdDSA. An empty id is replaced by the RFC 7638 thumbprint of the
+// public key.
+func ParsePrivateKeyPEM(data []byte, id string) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected block %q", ErrInvalidPEM, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPEM, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	key, err := newKey(signer.Public(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	key.signer, err = jwk.New(private)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.signer.Set(jwk.KeyIDKey, key.ID); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParsePublicKeyPEM reads a public key in PKIX or PKCS#1 form, or the public
+// half of a private key, to verify the tokens signed by it.
+func ParsePublicKeyPEM(data []byte, id string) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var public interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		private, err := ParsePrivateKeyPEM(data, id)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{ID: private.ID, Algorithm: private.Algorithm, public: private.public}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPEM, err)
+	}
+
+	return newKey(public, id)
+}
+
+func newKey(public interface{}, id string) (*Key, error) {
+	var alg jwa.SignatureAlgorithm
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		alg = jwa.RS256
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: only P-256 EC keys are supported", ErrUnsupportedKey)
+		}
+		alg = jwa.ES256
+	case ed25519.PublicKey:
+		alg = jwa.EdDSA
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+
+	if id == "" {
+		jwkKey, err := jwk.New(public)
+		if err != nil {
+			return nil, err
+		}
+		thumbprint, err := jwkKey.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		id = base64.RawURLEncoding.EncodeToString(thumbprint)
+	}
+
+	return &Key{ID: id, Algorithm: alg, public: public}, nil
+}
+
+// KeySet signs tokens with one key and verifies them with any of its keys.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	// order keeps the keys as given, for a stable JWKS.
+	order []*Key
+}
+
+// NewKeySet returns a set that signs with signing and also accepts tokens
+// signed by the verification keys, such as the previous signing key.
+func NewKeySet(signing *Key, verification ...*Key) (*KeySet, error) {
+	if signing.signer == nil {
+		return nil, ErrNotSigningKey
+	}
+
+	s := &KeySet{signing: signing, keys: make(map[string]*Key)}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if _, ok := s.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		s.keys[key.ID] = key
+		s.order = append(s.order, key)
+	}
+	return s, nil
+}
+
+// Encode signs the claims with the signing key, naming it in the kid header.
+// It has the signature of jwtauth.JWTAuth.Encode.
+func (s *KeySet) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	t := jwt.New()
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	signed, err := jwt.Sign(t, s.signing.Algorithm, s.signing.signer)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, string(signed), nil
+}
+
+// Decode verifies the signature of the token with the key of its kid and
+// returns it. The algorithm in the header has to be the one of the key, so
+// a token can't pick a weaker check. Tokens without kid, issued before keys
+// had IDs, are checked against the signing key.
+func (s *KeySet) Decode(tokenString string) (jwt.Token, error) {
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, errors.New("Token must have one signature")
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+
+	key := s.signing
+	if kid := headers.KeyID(); kid != "" {
+		var ok bool
+		if key, ok = s.keys[kid]; !ok {
+			return nil, ErrUnknownKeyID
+		}
+	}
+
+	if headers.Algorithm() != key.Algorithm {
+		return nil, ErrAlgorithmMismatch
+	}
+
+	return jwt.ParseString(tokenString, jwt.WithVerify(key.Algorithm, key.public))
+}
+
+// JWKS returns the public keys of the set as a JSON Web Key Set. Symmetric
+// keys are left out, since publishing them would let anyone sign tokens.
+func (s *KeySet) JWKS() ([]byte, error) {
+	keys := []jwk.Key{}
+	for _, key := range s.order {
+		if key.Algorithm == jwa.HS256 {
+			continue
+		}
+
+		public, err := jwk.New(key.public)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range map[string]interface{}{
+			jwk.KeyIDKey:     key.ID,
+			jwk.AlgorithmKey: key.Algorithm.String(),
+			jwk.KeyUsageKey:  "sig",
+		} {
+			if err := public.Set(name, value); err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, public)
+	}
+	return json.Marshal(map[string][]jwk.Key{"keys": keys})
+}
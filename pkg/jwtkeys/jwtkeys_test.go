@@ -0,0 +1,191 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+func privatePEM(t *testing.T, private interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, public interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name    string
+		private interface{}
+		alg     jwa.SignatureAlgorithm
+	}{
+		{"rsa", rsaKey, jwa.RS256},
+		{"ecdsa", ecKey, jwa.ES256},
+		{"ed25519", edKey, jwa.EdDSA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM(privatePEM(t, tt.private), "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.alg, key.Algorithm)
+			assert.NotEmpty(t, key.ID)
+
+			keys, err := NewKeySet(key)
+			assert.NoError(t, err)
+
+			_, signed, err := keys.Encode(map[string]interface{}{"sub": "user-1"})
+			assert.NoError(t, err)
+
+			msg, err := jws.ParseString(signed)
+			assert.NoError(t, err)
+			headers := msg.Signatures()[0].ProtectedHeaders()
+			assert.Equal(t, key.ID, headers.KeyID())
+			assert.Equal(t, tt.alg, headers.Algorithm())
+
+			token, err := keys.Decode(signed)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", token.Subject())
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldRSA, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newEC, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	oldKey, err := ParsePrivateKeyPEM(privatePEM(t, oldRSA), "2026-09")
+	assert.NoError(t, err)
+	oldKeys, _ := NewKeySet(oldKey)
+	_, oldToken, _ := oldKeys.Encode(map[string]interface{}{"sub": "user-1"})
+
+	// A chave antiga passa a só verificar, pela parte pública
+	newKey, _ := ParsePrivateKeyPEM(privatePEM(t, newEC), "2026-10")
+	previous, err := ParsePublicKeyPEM(publicPEM(t, &oldRSA.PublicKey), "2026-09")
+	assert.NoError(t, err)
+	keys, err := NewKeySet(newKey, previous)
+	assert.NoError(t, err)
+
+	_, err = keys.Decode(oldToken)
+	assert.NoError(t, err)
+
+	_, newToken, _ := keys.Encode(map[string]interface{}{"sub": "user-1"})
+	_, err = oldKeys.Decode(newToken)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+	_, err = NewKeySet(previous)
+	assert.ErrorIs(t, err, ErrNotSigningKey)
+	_, err = NewKeySet(newKey, newKey)
+	assert.Error(t, err)
+}
+
+func TestDecodeRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, _ := ParsePrivateKeyPEM(privatePEM(t, rsaKey), "rsa")
+	keys, _ := NewKeySet(key)
+
+	// Um token HS256 assinado com a chave pública RSA como segredo
+	forger, _ := NewSymmetricKey("rsa", publicPEM(t, &rsaKey.PublicKey))
+	forged, _ := NewKeySet(forger)
+	_, token, err := forged.Encode(map[string]interface{}{"sub": "admin"})
+	assert.NoError(t, err)
+
+	_, err = keys.Decode(token)
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+}
+
+func TestSymmetricKeyAcceptsTokensWithoutKeyID(t *testing.T) {
+	secret := []byte("secret")
+	key, err := NewSymmetricKey("default", secret)
+	assert.NoError(t, err)
+	keys, _ := NewKeySet(key)
+
+	// Tokens emitidos antes de existir kid
+	legacy, _ := NewSymmetricKey("", secret)
+	legacyKeys, _ := NewKeySet(legacy)
+	_, token, _ := legacyKeys.Encode(map[string]interface{}{"sub": "user-1"})
+
+	decoded, err := keys.Decode(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", decoded.Subject())
+
+	other, _ := NewSymmetricKey("default", []byte("other"))
+	otherKeys, _ := NewKeySet(other)
+	_, token, _ = otherKeys.Encode(map[string]interface{}{"sub": "user-1"})
+	_, err = keys.Decode(token)
+	assert.Error(t, err)
+}
+
+func TestSymmetricKeyRejectsEmptySecret(t *testing.T) {
+	_, err := NewSymmetricKey("default", nil)
+	assert.ErrorIs(t, err, ErrEmptySecret)
+}
+
+func TestJWKS(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	signing, _ := ParsePrivateKeyPEM(privatePEM(t, edKey), "ed")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	previous, _ := ParsePublicKeyPEM(privatePEM(t, rsaKey), "rsa")
+	secret, _ := NewSymmetricKey("hs", []byte("secret"))
+
+	keys, err := NewKeySet(signing, previous, secret)
+	assert.NoError(t, err)
+
+	data, err := keys.JWKS()
+	assert.NoError(t, err)
+
+	var jwks struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &jwks))
+	assert.Len(t, jwks.Keys, 2)
+
+	assert.Equal(t, "ed", jwks.Keys[0]["kid"])
+	assert.Equal(t, "EdDSA", jwks.Keys[0]["alg"])
+	assert.Equal(t, "OKP", jwks.Keys[0]["kty"])
+	assert.Equal(t, "sig", jwks.Keys[0]["use"])
+	assert.Equal(t, "rsa", jwks.Keys[1]["kid"])
+	assert.Equal(t, "RS256", jwks.Keys[1]["alg"])
+
+	// Nada privado é publicado
+	for _, key := range jwks.Keys {
+		assert.NotContains(t, key, "d")
+		assert.NotContains(t, key, "k")
+	}
+	assert.False(t, strings.Contains(string(data), `"hs"`))
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := ParsePrivateKeyPEM([]byte("not a key"), "")
+	assert.ErrorIs(t, err, ErrInvalidPEM)
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, err = ParsePrivateKeyPEM(privatePEM(t, p384), "")
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	_, err = ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}), "")
+	assert.ErrorIs(t, err, ErrInvalidPEM)
+}
@@ -0,0 +1,183 @@
+// Package mailer sends plain text emails. SMTPMailer delivers them through an
+// SMTP server; FileMailer and MemoryMailer keep them locally for development
+// and tests.
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mateusfaustino/go-rest-api-III/pkg/entity"
+)
+
+var (
+	ErrInvalidHeader = errors.New("Invalid email header")
+	ErrUnknownDriver = errors.New("Unknown mail driver")
+)
+
+// Message is a plain text email.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Config selects and configures the mailer built by New.
+type Config struct {
+	// Driver is smtp, file or memory.
+	Driver string
+	From   string
+	// Dir is where the file driver writes the messages.
+	Dir string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// New builds the mailer of the configured driver. Messages without a sender
+// are sent from cfg.From.
+func New(cfg Config) (Mailer, error) {
+	var m Mailer
+	switch cfg.Driver {
+	case "smtp":
+		m = NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword)
+	case "file":
+		m = NewFileMailer(cfg.Dir)
+	case "memory":
+		m = NewMemoryMailer()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, cfg.Driver)
+	}
+	return withSender{m, cfg.From}, nil
+}
+
+type withSender struct {
+	Mailer
+	from string
+}
+
+func (s withSender) Send(msg Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	return s.Mailer.Send(msg)
+}
+
+// Format renders the message as RFC 5322 text. Header values can't contain
+// line breaks, so user input can't add headers of its own.
+func Format(msg Message, date time.Time) ([]byte, error) {
+	for _, value := range []string{msg.From, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes(), nil
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the
+// server offers it. Without a username no authentication is done.
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+}
+
+func NewSMTPMailer(host string, port int, username, password string) *SMTPMailer {
+	m := &SMTPMailer{Addr: net.JoinHostPort(host, strconv.Itoa(port))}
+	if username != "" {
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	data, err := Format(msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, m.Auth, msg.From, []string{msg.To}, data)
+}
+
+// FileMailer writes each message to its own .eml file, so they can be opened
+// with any mail client during development.
+type FileMailer struct {
+	Dir string
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	now := time.Now()
+	data, err := Format(msg, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix, err := entity.NewRandomToken(6)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), suffix)
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+}
+
+// MemoryMailer keeps the sent messages in memory.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	if _, err := Format(msg, time.Now()); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.messages = append(m.messages, msg)
+	m.mu.Unlock()
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	data, err := Format(Message{From: "shop@example.com", To: "ana@example.com", Subject: "Redefinição de senha", Body: "Olá\nclique no link"}, date)
+	assert.NoError(t, err)
+
+	text := string(data)
+	assert.True(t, strings.HasPrefix(text, "From: shop@example.com\r\nTo: ana@example.com\r\n"))
+	assert.Contains(t, text, "Subject: =?utf-8?q?Redefini=C3=A7=C3=A3o_de_senha?=\r\n")
+	assert.Contains(t, text, "Date: Sun, 18 Oct 2026 12:00:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(text, "\r\n\r\nOlá\r\nclique no link"))
+}
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	_, err := Format(Message{From: "shop@example.com", To: "ana@example.com\r\nBcc: eve@example.com", Subject: "Hi"}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+
+	_, err = Format(Message{From: "shop@example.com", To: "ana@example.com", Subject: "Hi\nBcc: eve@example.com"}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(dir)
+
+	assert.NoError(t, m.Send(Message{From: "shop@example.com", To: "ana@example.com", Subject: "Hi", Body: "first"}))
+	assert.NoError(t, m.Send(Message{From: "shop@example.com", To: "ana@example.com", Subject: "Hi", Body: "second"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: ana@example.com\r\n")
+}
+
+func TestNewUsesDefaultSender(t *testing.T) {
+	m, err := New(Config{Driver: "memory", From: "shop@example.com"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.Send(Message{To: "ana@example.com", Subject: "Hi"}))
+	assert.NoError(t, m.Send(Message{From: "sales@example.com", To: "ana@example.com", Subject: "Hi"}))
+
+	messages := m.(withSender).Mailer.(*MemoryMailer).Messages()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "shop@example.com", messages[0].From)
+	assert.Equal(t, "sales@example.com", messages[1].From)
+
+	_, err = New(Config{Driver: "pigeon"})
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+}
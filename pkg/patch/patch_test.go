@@ -0,0 +1,75 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	changes := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`
+
+	result, err := Merge([]byte(doc), []byte(changes))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`, string(result))
+
+	_, err = Merge([]byte(doc), []byte(`{bad`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"foo":"bar","list":["a","b"],"obj":{"x":1}}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"add member", `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux","list":["a","b"],"obj":{"x":1}}`},
+		{"insert in array", `[{"op":"add","path":"/list/1","value":"z"}]`, `{"foo":"bar","list":["a","z","b"],"obj":{"x":1}}`},
+		{"append to array", `[{"op":"add","path":"/list/-","value":"c"}]`, `{"foo":"bar","list":["a","b","c"],"obj":{"x":1}}`},
+		{"remove", `[{"op":"remove","path":"/list/0"}]`, `{"foo":"bar","list":["b"],"obj":{"x":1}}`},
+		{"replace", `[{"op":"replace","path":"/obj/x","value":null}]`, `{"foo":"bar","list":["a","b"],"obj":{"x":null}}`},
+		{"move", `[{"op":"move","from":"/foo","path":"/obj/foo"}]`, `{"list":["a","b"],"obj":{"x":1,"foo":"bar"}}`},
+		{"copy", `[{"op":"copy","from":"/obj","path":"/copy"}]`, `{"foo":"bar","list":["a","b"],"obj":{"x":1},"copy":{"x":1}}`},
+		{"test then replace", `[{"op":"test","path":"/foo","value":"bar"},{"op":"replace","path":"/foo","value":"baz"}]`, `{"foo":"baz","list":["a","b"],"obj":{"x":1}}`},
+		{"escaped pointer", `[{"op":"add","path":"/a~1b~0c","value":1}]`, `{"foo":"bar","a/b~c":1,"list":["a","b"],"obj":{"x":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply([]byte(doc), []byte(tt.patch))
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(result))
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	doc := []byte(`{"foo":"bar","list":["a"]}`)
+
+	_, err := Apply(doc, []byte(`[{"op":"test","path":"/foo","value":"baz"}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+
+	_, err = Apply(doc, []byte(`[{"op":"replace","path":"/missing","value":1}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	_, err = Apply(doc, []byte(`[{"op":"remove","path":"/list/1"}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	_, err = Apply(doc, []byte(`[{"op":"add","path":"/list/01","value":1}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	_, err = Apply(doc, []byte(`[{"op":"add","path":"/foo"}]`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	_, err = Apply(doc, []byte(`[{"op":"jump","path":"/foo"}]`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	_, err = Apply(doc, []byte(`[{"op":"move","from":"/list","path":"/list/0"}]`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	_, err = Apply(doc, []byte(`{"op":"add"}`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
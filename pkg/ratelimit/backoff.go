@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// BackoffDelay is how long to wait after the given number of failures in a
+// row: nothing for the first free ones, then base, doubling with each
+// failure up to max.
+func BackoffDelay(failures, free int, base, max time.Duration) time.Duration {
+	if failures <= free {
+		return 0
+	}
+
+	delay := base
+	for i := free + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// Backoff blocks a key, such as an IP address, for longer after each
+// failure, following BackoffDelay. The failures of a key are forgotten once
+// it goes reset without new ones. Like Limiter, it lives in memory.
+type Backoff struct {
+	free  int
+	base  time.Duration
+	max   time.Duration
+	reset time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*backoffEntry
+	lastEvict time.Time
+}
+
+type backoffEntry struct {
+	failures int
+	last     time.Time
+	until    time.Time
+}
+
+func NewBackoff(free int, base, max, reset time.Duration) *Backoff {
+	return &Backoff{
+		free:    free,
+		base:    base,
+		max:     max,
+		reset:   reset,
+		now:     time.Now,
+		entries: make(map[string]*backoffEntry),
+	}
+}
+
+// Wait returns how long the key is still blocked, zero when it isn't.
+func (b *Backoff) Wait(key string) time.Duration {
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[key]
+	if !ok || !entry.until.After(now) {
+		return 0
+	}
+	return entry.until.Sub(now)
+}
+
+// Fail records a failure of the key and returns how long it is blocked for.
+func (b *Backoff) Fail(key string) time.Duration {
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evictExpired(now)
+
+	entry, ok := b.entries[key]
+	if !ok || now.Sub(entry.last) >= b.reset {
+		entry = &backoffEntry{}
+		b.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.last = now
+	delay := BackoffDelay(entry.failures, b.free, b.base, b.max)
+	entry.until = now.Add(delay)
+	return delay
+}
+
+// evictExpired forgets the keys whose failures were reset, at most once per
+// reset period.
+func (b *Backoff) evictExpired(now time.Time) {
+	if now.Sub(b.lastEvict) < b.reset {
+		return
+	}
+	b.lastEvict = now
+
+	for key, entry := range b.entries {
+		if now.Sub(entry.last) >= b.reset {
+			delete(b.entries, key)
+		}
+	}
+}
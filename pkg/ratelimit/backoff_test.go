@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{12, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, BackoffDelay(tt.failures, 3, time.Second, time.Minute), "failures %d", tt.failures)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	b := NewBackoff(1, time.Second, time.Minute, time.Hour)
+	b.now = func() time.Time { return now }
+
+	assert.Zero(t, b.Fail("10.0.0.1"))
+	assert.Zero(t, b.Wait("10.0.0.1"))
+
+	assert.Equal(t, time.Second, b.Fail("10.0.0.1"))
+	assert.Equal(t, 2*time.Second, b.Fail("10.0.0.1"))
+	assert.Equal(t, 2*time.Second, b.Wait("10.0.0.1"))
+
+	// Outras chaves não são afetadas
+	assert.Zero(t, b.Wait("10.0.0.2"))
+
+	now = now.Add(2 * time.Second)
+	assert.Zero(t, b.Wait("10.0.0.1"))
+
+	// Depois do período sem falhas, a contagem recomeça
+	now = now.Add(time.Hour)
+	assert.Zero(t, b.Fail("10.0.0.1"))
+	assert.Len(t, b.entries, 1)
+}
@@ -0,0 +1,77 @@
+// Package ratelimit limits how often something can happen per key, such as
+// an email address, over a sliding window.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows up to limit events per key in any window. It lives in
+// memory, so each instance of the server keeps its own count.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	lastEvict time.Time
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		events: make(map[string][]time.Time),
+	}
+}
+
+// Allow records an event for the key when it is within the limit. Otherwise
+// nothing is recorded and the time until the next event is allowed is
+// returned.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.evictExpired(now)
+
+	events := l.recent(key, now)
+	if len(events) >= l.limit {
+		return false, events[0].Add(l.window).Sub(now)
+	}
+
+	l.events[key] = append(events, now)
+	return true, 0
+}
+
+// recent drops the events of the key that left the window.
+func (l *Limiter) recent(key string, now time.Time) []time.Time {
+	events := l.events[key]
+	start := now.Add(-l.window)
+	i := 0
+	for i < len(events) && !events[i].After(start) {
+		i++
+	}
+	return events[i:]
+}
+
+// evictExpired forgets the keys without recent events, at most once per
+// window, so the map doesn't grow forever.
+func (l *Limiter) evictExpired(now time.Time) {
+	if now.Sub(l.lastEvict) < l.window {
+		return
+	}
+	l.lastEvict = now
+
+	for key := range l.events {
+		if events := l.recent(key, now); len(events) == 0 {
+			delete(l.events, key)
+		} else {
+			l.events[key] = events
+		}
+	}
+}
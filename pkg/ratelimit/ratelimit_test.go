@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Hour)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("ana@example.com")
+	assert.True(t, ok)
+
+	now = now.Add(10 * time.Minute)
+	ok, _ = l.Allow("ana@example.com")
+	assert.True(t, ok)
+
+	now = now.Add(10 * time.Minute)
+	ok, retry := l.Allow("ana@example.com")
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Minute, retry)
+
+	// Outras chaves têm o seu próprio limite
+	ok, _ = l.Allow("bruno@example.com")
+	assert.True(t, ok)
+
+	// Quando o primeiro evento sai da janela, abre espaço para mais um
+	now = now.Add(40 * time.Minute)
+	ok, _ = l.Allow("ana@example.com")
+	assert.True(t, ok)
+	ok, _ = l.Allow("ana@example.com")
+	assert.False(t, ok)
+}
+
+func TestLimiterEvictsIdleKeys(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("ana@example.com")
+	l.Allow("bruno@example.com")
+	assert.Len(t, l.events, 2)
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("carla@example.com")
+	assert.Len(t, l.events, 1)
+}
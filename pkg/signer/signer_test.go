@@ -0,0 +1,27 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	s := New([]byte("secret"), "cursor")
+
+	token := s.Sign([]byte(`{"id":"1"}`))
+	payload, err := s.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"1"}`, string(payload))
+
+	tampered := s.Sign([]byte(`{"id":"2"}`))
+	_, err = s.Verify(token[:len(token)-2] + tampered[len(tampered)-2:])
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	_, err = s.Verify("garbage")
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// Outra finalidade, outra chave
+	_, err = New([]byte("secret"), "email").Verify(token)
+	assert.Equal(t, ErrInvalidSignature, err)
+}